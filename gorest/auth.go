@@ -0,0 +1,240 @@
+package gorest
+
+import (
+	"crypto/md5"
+	"crypto/rand"
+	"crypto/sha256"
+	"encoding/base64"
+	"encoding/hex"
+	"fmt"
+	"hash"
+	"net/http"
+	"strings"
+	"sync"
+)
+
+// WithBasicAuth sets an "Authorization: Basic" header with the given credentials.
+func (r *Request) WithBasicAuth(username, password string) *Request {
+	credentials := base64.StdEncoding.EncodeToString([]byte(username + ":" + password))
+	return r.WithHeader("Authorization", "Basic "+credentials)
+}
+
+// APIKeyLocation selects where the APIKeyMiddleware places the key.
+type APIKeyLocation int
+
+const (
+	// APIKeyInHeader sends the key in a request header.
+	APIKeyInHeader APIKeyLocation = iota
+	// APIKeyInQuery sends the key as a query parameter.
+	APIKeyInQuery
+)
+
+// APIKeyMiddleware returns a middleware that adds an API key to every request,
+// as the header or query parameter called name depending on in.
+func APIKeyMiddleware(name, key string, in APIKeyLocation) Middleware {
+	return func(next RoundTripFunc) RoundTripFunc {
+		return func(req *http.Request) (*http.Response, error) {
+			req = req.Clone(req.Context())
+			switch in {
+			case APIKeyInQuery:
+				query := req.URL.Query()
+				query.Set(name, key)
+				req.URL.RawQuery = query.Encode()
+			default:
+				req.Header.Set(name, key)
+			}
+			return next(req)
+		}
+	}
+}
+
+// DigestAuthMiddleware returns a middleware that answers HTTP Digest challenges (RFC 7616).
+// When a request is rejected with a 401 and a "WWW-Authenticate: Digest" challenge, it is retried once with
+// credentials. The challenge is then reused for later requests to the same host, counting nonces,
+// until the server issues a new one. The MD5, MD5-sess, SHA-256 and SHA-256-sess algorithms are supported,
+// with qop=auth or without qop.
+// Note: The request body is fully buffered in memory so that the request can be retried.
+func DigestAuthMiddleware(username, password string) Middleware {
+	var mu sync.Mutex
+	challenges := make(map[string]*digestChallenge)
+
+	return func(next RoundTripFunc) RoundTripFunc {
+		return func(req *http.Request) (*http.Response, error) {
+			bodyBytes, err := bufferRequestBody(req)
+			if err != nil {
+				return nil, err
+			}
+			send := func(challenge *digestChallenge) (*http.Response, error) {
+				attempt := cloneWithBody(req, bodyBytes)
+				if challenge != nil {
+					attempt.Header.Set("Authorization", challenge.authorize(username, password, req.Method, req.URL.RequestURI()))
+				}
+				return next(attempt)
+			}
+
+			mu.Lock()
+			challenge := challenges[req.URL.Host]
+			mu.Unlock()
+
+			resp, err := send(challenge)
+			if err != nil || resp.StatusCode != http.StatusUnauthorized {
+				return resp, err
+			}
+			fresh := parseDigestChallenge(resp.Header.Values("WWW-Authenticate"))
+			if fresh == nil || (challenge != nil && fresh.nonce == challenge.nonce) {
+				// Unsupported challenge, or the credentials were rejected.
+				return resp, nil
+			}
+			DrainAndClose(resp)
+
+			mu.Lock()
+			challenges[req.URL.Host] = fresh
+			mu.Unlock()
+			return send(fresh)
+		}
+	}
+}
+
+// digestChallenge is a Digest challenge from a WWW-Authenticate header.
+type digestChallenge struct {
+	realm     string
+	nonce     string
+	opaque    string
+	algorithm string
+	// qop is "auth", or empty for servers that do not send a qop (RFC 2069 compatibility).
+	qop string
+
+	mu sync.Mutex
+	nc uint32
+}
+
+// digestAlgorithms maps the supported algorithms to their hash functions.
+var digestAlgorithms = map[string]func() hash.Hash{
+	"MD5":          md5.New,
+	"MD5-SESS":     md5.New,
+	"SHA-256":      sha256.New,
+	"SHA-256-SESS": sha256.New,
+}
+
+// parseDigestChallenge returns the strongest supported Digest challenge among the WWW-Authenticate header values.
+func parseDigestChallenge(values []string) *digestChallenge {
+	var best *digestChallenge
+	for _, value := range values {
+		scheme, rest, _ := strings.Cut(strings.TrimSpace(value), " ")
+		if !strings.EqualFold(scheme, "Digest") {
+			continue
+		}
+		params := parseAuthParams(rest)
+		c := &digestChallenge{
+			realm:     params["realm"],
+			nonce:     params["nonce"],
+			opaque:    params["opaque"],
+			algorithm: params["algorithm"],
+		}
+		if c.algorithm == "" {
+			c.algorithm = "MD5"
+		}
+		if _, ok := digestAlgorithms[strings.ToUpper(c.algorithm)]; !ok || c.nonce == "" {
+			continue
+		}
+		if qop, ok := params["qop"]; ok {
+			for _, option := range strings.Split(qop, ",") {
+				if strings.TrimSpace(option) == "auth" {
+					c.qop = "auth"
+				}
+			}
+			if c.qop == "" {
+				// Only auth-int is offered, which is not supported.
+				continue
+			}
+		}
+		if best == nil || (c.isSHA256() && !best.isSHA256()) {
+			best = c
+		}
+	}
+	return best
+}
+
+func (c *digestChallenge) isSHA256() bool {
+	return strings.HasPrefix(strings.ToUpper(c.algorithm), "SHA-256")
+}
+
+// parseAuthParams parses comma-separated auth parameters, unquoting quoted values.
+// Parameter names are lowercased.
+func parseAuthParams(s string) map[string]string {
+	params := make(map[string]string)
+	for {
+		s = strings.TrimLeft(s, " \t,")
+		if s == "" {
+			return params
+		}
+		name, rest, ok := strings.Cut(s, "=")
+		if !ok {
+			return params
+		}
+		name = strings.ToLower(strings.TrimSpace(name))
+		rest = strings.TrimLeft(rest, " \t")
+		var value strings.Builder
+		if strings.HasPrefix(rest, `"`) {
+			i := 1
+			for ; i < len(rest) && rest[i] != '"'; i++ {
+				if rest[i] == '\\' && i+1 < len(rest) {
+					i++
+				}
+				value.WriteByte(rest[i])
+			}
+			s = rest[min(i+1, len(rest)):]
+		} else {
+			end := strings.IndexByte(rest, ',')
+			if end < 0 {
+				end = len(rest)
+			}
+			value.WriteString(strings.TrimSpace(rest[:end]))
+			s = rest[end:]
+		}
+		params[name] = value.String()
+	}
+}
+
+// authorize returns the Authorization header value for a request, counting the nonce use.
+func (c *digestChallenge) authorize(username, password, method, uri string) string {
+	c.mu.Lock()
+	c.nc++
+	nc := fmt.Sprintf("%08x", c.nc)
+	c.mu.Unlock()
+	cnonce := newCnonce()
+
+	h := func(s string) string {
+		hasher := digestAlgorithms[strings.ToUpper(c.algorithm)]()
+		hasher.Write([]byte(s))
+		return hex.EncodeToString(hasher.Sum(nil))
+	}
+	ha1 := h(username + ":" + c.realm + ":" + password)
+	if strings.HasSuffix(strings.ToUpper(c.algorithm), "-SESS") {
+		ha1 = h(ha1 + ":" + c.nonce + ":" + cnonce)
+	}
+	ha2 := h(method + ":" + uri)
+	var response string
+	if c.qop == "" {
+		response = h(ha1 + ":" + c.nonce + ":" + ha2)
+	} else {
+		response = h(ha1 + ":" + c.nonce + ":" + nc + ":" + cnonce + ":" + c.qop + ":" + ha2)
+	}
+
+	var b strings.Builder
+	fmt.Fprintf(&b, `Digest username="%s", realm="%s", nonce="%s", uri="%s", algorithm=%s, response="%s"`,
+		escapeQuotes(username), escapeQuotes(c.realm), escapeQuotes(c.nonce), escapeQuotes(uri), c.algorithm, response)
+	if c.qop != "" {
+		fmt.Fprintf(&b, `, qop=%s, nc=%s, cnonce="%s"`, c.qop, nc, cnonce)
+	}
+	if c.opaque != "" {
+		fmt.Fprintf(&b, `, opaque="%s"`, escapeQuotes(c.opaque))
+	}
+	return b.String()
+}
+
+func newCnonce() string {
+	b := make([]byte, 16)
+	_, _ = rand.Read(b)
+	return hex.EncodeToString(b)
+}
@@ -0,0 +1,171 @@
+package gorest_test
+
+import (
+	"context"
+	"crypto/md5"
+	"crypto/sha256"
+	"encoding/hex"
+	"fmt"
+	"hash"
+	"io"
+	"net/http"
+	"net/http/httptest"
+	"regexp"
+	"strings"
+	"sync"
+
+	. "github.com/onsi/ginkgo/v2"
+	. "github.com/onsi/gomega"
+
+	"gorest/gorest"
+)
+
+var _ = Describe("Authentication", func() {
+	It("should set basic auth credentials", func() {
+		httpReq, err := gorest.NewRequest("GET", "http://example.com").WithBasicAuth("user", "pa:ss").BuildHTTPRequest()
+		Expect(err).NotTo(HaveOccurred())
+		username, password, ok := httpReq.BasicAuth()
+		Expect(ok).To(BeTrue())
+		Expect(username).To(Equal("user"))
+		Expect(password).To(Equal("pa:ss"))
+	})
+
+	Describe("APIKeyMiddleware", func() {
+		var seen *http.Request
+		next := func(req *http.Request) (*http.Response, error) {
+			seen = req
+			return &http.Response{StatusCode: 200, Body: http.NoBody}, nil
+		}
+
+		It("should send the key in a header", func() {
+			req, _ := http.NewRequest("GET", "http://example.com/items", nil)
+			_, err := gorest.APIKeyMiddleware("X-API-Key", "k3y", gorest.APIKeyInHeader)(next)(req)
+			Expect(err).NotTo(HaveOccurred())
+			Expect(seen.Header.Get("X-API-Key")).To(Equal("k3y"))
+			Expect(req.Header.Get("X-API-Key")).To(BeEmpty())
+		})
+
+		It("should send the key as a query parameter", func() {
+			req, _ := http.NewRequest("GET", "http://example.com/items?page=2", nil)
+			_, err := gorest.APIKeyMiddleware("api_key", "k3y", gorest.APIKeyInQuery)(next)(req)
+			Expect(err).NotTo(HaveOccurred())
+			Expect(seen.URL.Query().Get("api_key")).To(Equal("k3y"))
+			Expect(seen.URL.Query().Get("page")).To(Equal("2"))
+			Expect(req.URL.RawQuery).To(Equal("page=2"))
+		})
+	})
+
+	Describe("DigestAuthMiddleware", func() {
+		var (
+			mu        sync.Mutex
+			requests  int
+			ncs       []string
+			nonce     string
+			algorithm string
+			qop       string
+			server    *httptest.Server
+		)
+		paramPattern := regexp.MustCompile(`(\w+)=(?:"([^"]*)"|([^,\s]+))`)
+
+		BeforeEach(func() {
+			requests = 0
+			ncs = nil
+			nonce = "dcd98b7102dd2f0e8b11d0f600bfb0c093"
+			algorithm = "MD5"
+			qop = "auth"
+			server = httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+				mu.Lock()
+				defer mu.Unlock()
+				requests++
+				body, _ := io.ReadAll(r.Body)
+
+				params := map[string]string{}
+				for _, m := range paramPattern.FindAllStringSubmatch(strings.TrimPrefix(r.Header.Get("Authorization"), "Digest "), -1) {
+					params[m[1]] = m[2] + m[3]
+				}
+				newHash := map[string]func() hash.Hash{"MD5": md5.New, "SHA-256": sha256.New, "SHA-256-sess": sha256.New}[algorithm]
+				h := func(s string) string {
+					hasher := newHash()
+					hasher.Write([]byte(s))
+					return hex.EncodeToString(hasher.Sum(nil))
+				}
+				ha1 := h("Mufasa:testrealm@host.com:Circle of Life")
+				if strings.HasSuffix(algorithm, "-sess") {
+					ha1 = h(ha1 + ":" + nonce + ":" + params["cnonce"])
+				}
+				ha2 := h(r.Method + ":" + r.URL.RequestURI())
+				expected := h(ha1 + ":" + nonce + ":" + ha2)
+				if qop != "" {
+					expected = h(ha1 + ":" + nonce + ":" + params["nc"] + ":" + params["cnonce"] + ":auth:" + ha2)
+				}
+
+				if params["nonce"] != nonce || params["response"] != expected || params["opaque"] != "5ccc069c" ||
+					params["algorithm"] != algorithm || params["uri"] != r.URL.RequestURI() {
+					challenge := fmt.Sprintf(`Digest realm="testrealm@host.com", nonce="%s", opaque="5ccc069c", algorithm=%s`, nonce, algorithm)
+					if qop != "" {
+						challenge += `, qop="auth,auth-int"`
+					}
+					w.Header().Add("WWW-Authenticate", `Basic realm="testrealm@host.com"`)
+					w.Header().Add("WWW-Authenticate", challenge)
+					w.WriteHeader(http.StatusUnauthorized)
+					return
+				}
+				ncs = append(ncs, params["nc"])
+				_, _ = fmt.Fprintf(w, "welcome %s", body)
+			}))
+		})
+
+		AfterEach(func() {
+			server.Close()
+		})
+
+		send := func(client *gorest.Client, method string) *gorest.Response {
+			resp, err := client.Do(context.Background(), gorest.NewRequest(method, server.URL+"/dir/index.html?x=1").WithBody([]byte("body")))
+			Expect(err).NotTo(HaveOccurred())
+			return resp
+		}
+
+		for _, alg := range []string{"MD5", "SHA-256", "SHA-256-sess"} {
+			It("should answer a "+alg+" challenge", func() {
+				algorithm = alg
+				client := gorest.NewClient(gorest.WithMiddlewares(gorest.DigestAuthMiddleware("Mufasa", "Circle of Life")))
+				resp := send(client, "POST")
+				Expect(resp.StatusCode).To(Equal(200))
+				body, _ := resp.Bytes()
+				Expect(string(body)).To(Equal("welcome body"))
+			})
+		}
+
+		It("should reuse the challenge and count nonces", func() {
+			client := gorest.NewClient(gorest.WithMiddlewares(gorest.DigestAuthMiddleware("Mufasa", "Circle of Life")))
+			for i := 0; i < 3; i++ {
+				Expect(send(client, "GET").StatusCode).To(Equal(200))
+			}
+			Expect(requests).To(Equal(4))
+			Expect(ncs).To(Equal([]string{"00000001", "00000002", "00000003"}))
+		})
+
+		It("should pick up a new nonce", func() {
+			client := gorest.NewClient(gorest.WithMiddlewares(gorest.DigestAuthMiddleware("Mufasa", "Circle of Life")))
+			Expect(send(client, "GET").StatusCode).To(Equal(200))
+			mu.Lock()
+			nonce = "fresh-nonce"
+			mu.Unlock()
+			Expect(send(client, "GET").StatusCode).To(Equal(200))
+			Expect(ncs).To(Equal([]string{"00000001", "00000001"}))
+		})
+
+		It("should support servers without qop", func() {
+			qop = ""
+			client := gorest.NewClient(gorest.WithMiddlewares(gorest.DigestAuthMiddleware("Mufasa", "Circle of Life")))
+			Expect(send(client, "GET").StatusCode).To(Equal(200))
+		})
+
+		It("should return the 401 when the credentials are wrong", func() {
+			client := gorest.NewClient(gorest.WithMiddlewares(gorest.DigestAuthMiddleware("Mufasa", "wrong")))
+			Expect(send(client, "GET").StatusCode).To(Equal(http.StatusUnauthorized))
+			Expect(send(client, "GET").StatusCode).To(Equal(http.StatusUnauthorized))
+			Expect(requests).To(Equal(3))
+		})
+	})
+})
@@ -0,0 +1,60 @@
+package gorest
+
+import (
+	"io"
+	"net/http"
+	"sync"
+)
+
+// BulkheadMiddleware returns a middleware that allows at most limit requests in flight at once.
+// A request holds its slot until its response body is read to the end or closed, or until next returns an error.
+// Releasing at the end of the body keeps slots from leaking when middlewares replace the body without closing it.
+// Requests over the limit wait for a free slot; if the request context is done first, the context error is returned.
+func BulkheadMiddleware(limit int) Middleware {
+	if limit <= 0 {
+		limit = 1
+	}
+	slots := make(chan struct{}, limit)
+	return func(next RoundTripFunc) RoundTripFunc {
+		return func(req *http.Request) (*http.Response, error) {
+			select {
+			case slots <- struct{}{}:
+			case <-req.Context().Done():
+				return nil, req.Context().Err()
+			}
+			release := sync.OnceFunc(func() { <-slots })
+
+			resp, err := next(req)
+			if err != nil || resp.Body == nil {
+				release()
+				return resp, err
+			}
+			resp.Body = &releaseOnEOF{releaseOnClose{ReadCloser: resp.Body, release: release}}
+			return resp, nil
+		}
+	}
+}
+
+// releaseOnClose calls release once the body is closed.
+type releaseOnClose struct {
+	io.ReadCloser
+	release func()
+}
+
+func (r *releaseOnClose) Close() error {
+	defer r.release()
+	return r.ReadCloser.Close()
+}
+
+// releaseOnEOF calls release once the body is closed or a read returns an error, including io.EOF.
+type releaseOnEOF struct {
+	releaseOnClose
+}
+
+func (r *releaseOnEOF) Read(p []byte) (int, error) {
+	n, err := r.ReadCloser.Read(p)
+	if err != nil {
+		r.release()
+	}
+	return n, err
+}
@@ -0,0 +1,183 @@
+package gorest_test
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"io"
+	"net/http"
+	"strings"
+	"sync/atomic"
+	"time"
+
+	. "github.com/onsi/ginkgo/v2"
+	. "github.com/onsi/gomega"
+
+	"gorest/gorest"
+)
+
+// concurrencyTracker is a round trip that records the highest number of concurrent calls.
+type concurrencyTracker struct {
+	inFlight, peak, calls int32
+	delay                 time.Duration
+	fail                  string
+}
+
+func (t *concurrencyTracker) RoundTrip(req *http.Request) (*http.Response, error) {
+	atomic.AddInt32(&t.calls, 1)
+	n := atomic.AddInt32(&t.inFlight, 1)
+	defer atomic.AddInt32(&t.inFlight, -1)
+	for {
+		peak := atomic.LoadInt32(&t.peak)
+		if n <= peak || atomic.CompareAndSwapInt32(&t.peak, peak, n) {
+			break
+		}
+	}
+	select {
+	case <-time.After(t.delay):
+	case <-req.Context().Done():
+		return nil, req.Context().Err()
+	}
+	if t.fail != "" && req.URL.Path == t.fail {
+		return nil, errors.New("boom")
+	}
+	return &http.Response{
+		StatusCode: 200,
+		Header:     http.Header{},
+		Body:       io.NopCloser(strings.NewReader(req.URL.Path)),
+	}, nil
+}
+
+var _ = Describe("BulkheadMiddleware", func() {
+	It("should hold a slot until the response body is closed", func() {
+		next := func(req *http.Request) (*http.Response, error) {
+			return &http.Response{StatusCode: 200, Body: io.NopCloser(strings.NewReader(""))}, nil
+		}
+		rt := gorest.BulkheadMiddleware(1)(next)
+		req, _ := http.NewRequest("GET", "http://example.com", nil)
+		resp, err := rt(req)
+		Expect(err).NotTo(HaveOccurred())
+
+		ctx, cancel := context.WithTimeout(context.Background(), 20*time.Millisecond)
+		defer cancel()
+		_, err = rt(req.WithContext(ctx))
+		Expect(errors.Is(err, context.DeadlineExceeded)).To(BeTrue())
+
+		Expect(resp.Body.Close()).To(Succeed())
+		Expect(resp.Body.Close()).To(Succeed())
+		resp, err = rt(req)
+		Expect(err).NotTo(HaveOccurred())
+		Expect(resp.Body.Close()).To(Succeed())
+	})
+
+	It("should release the slot when the response body is read to the end", func() {
+		next := func(req *http.Request) (*http.Response, error) {
+			return &http.Response{StatusCode: 200, Body: io.NopCloser(strings.NewReader("ok"))}, nil
+		}
+		rt := gorest.BulkheadMiddleware(1)(next)
+		req, _ := http.NewRequest("GET", "http://example.com", nil)
+		resp, err := rt(req)
+		Expect(err).NotTo(HaveOccurred())
+		body, _ := io.ReadAll(resp.Body)
+		Expect(string(body)).To(Equal("ok"))
+
+		ctx, cancel := context.WithTimeout(context.Background(), time.Second)
+		defer cancel()
+		resp, err = rt(req.WithContext(ctx))
+		Expect(err).NotTo(HaveOccurred())
+		Expect(resp.Body.Close()).To(Succeed())
+	})
+
+	It("should release the slot when next fails", func() {
+		next := func(req *http.Request) (*http.Response, error) {
+			return nil, errors.New("boom")
+		}
+		rt := gorest.BulkheadMiddleware(1)(next)
+		req, _ := http.NewRequest("GET", "http://example.com", nil)
+		for i := 0; i < 3; i++ {
+			_, err := rt(req)
+			Expect(err).To(MatchError("boom"))
+		}
+	})
+})
+
+var _ = Describe("Concurrency limits", func() {
+	requests := func(n int) []*gorest.Request {
+		reqs := make([]*gorest.Request, n)
+		for i := range reqs {
+			reqs[i] = gorest.NewRequest("GET", fmt.Sprintf("http://example.com/%d", i))
+		}
+		return reqs
+	}
+
+	It("should limit in-flight requests with WithMaxConcurrency", func() {
+		tracker := &concurrencyTracker{delay: 5 * time.Millisecond}
+		client := gorest.NewClient(gorest.WithTransport(tracker), gorest.WithMaxConcurrency(3))
+		reqs := requests(20)
+		channels := make([]<-chan gorest.AsyncResponse, len(reqs))
+		for i, req := range reqs {
+			channels[i] = client.DoAsync(context.Background(), req)
+		}
+		for _, res := range <-client.JoinAsyncResponses(channels...) {
+			Expect(res.Error).NotTo(HaveOccurred())
+		}
+		Expect(atomic.LoadInt32(&tracker.peak)).To(Equal(int32(3)))
+	})
+
+	It("should free the slots of responses whose body is replaced by a middleware", func() {
+		tracker := &concurrencyTracker{}
+		client := gorest.NewClient(
+			gorest.WithTransport(tracker),
+			gorest.WithMaxConcurrency(1),
+			gorest.WithMiddlewares(gorest.LoggingMiddleware(io.Discard)),
+		)
+		ctx, cancel := context.WithTimeout(context.Background(), time.Second)
+		defer cancel()
+		for _, req := range requests(2) {
+			_, err := client.Do(ctx, req)
+			Expect(err).NotTo(HaveOccurred())
+		}
+		Expect(atomic.LoadInt32(&tracker.calls)).To(Equal(int32(2)))
+	})
+
+	It("should bound DoGroupAsync and keep the results in order", func() {
+		tracker := &concurrencyTracker{delay: 5 * time.Millisecond}
+		client := gorest.NewClient(gorest.WithTransport(tracker))
+		results := <-client.DoGroupAsync(context.Background(), requests(50)...)
+		Expect(results).To(HaveLen(50))
+		for i, res := range results {
+			Expect(res.Error).NotTo(HaveOccurred())
+			body, err := res.Response.Bytes()
+			Expect(err).NotTo(HaveOccurred())
+			Expect(string(body)).To(Equal(fmt.Sprintf("/%d", i)))
+		}
+		Expect(atomic.LoadInt32(&tracker.peak)).To(BeNumerically("<=", 16))
+	})
+
+	Describe("DoGroup", func() {
+		It("should use the worker limit and keep the responses in order", func() {
+			tracker := &concurrencyTracker{delay: 5 * time.Millisecond}
+			client := gorest.NewClient(gorest.WithTransport(tracker))
+			responses, err := client.DoGroup(context.Background(), &gorest.GroupOptions{Concurrency: 4}, requests(20)...)
+			Expect(err).NotTo(HaveOccurred())
+			Expect(responses).To(HaveLen(20))
+			for i, res := range responses {
+				body, err := res.Bytes()
+				Expect(err).NotTo(HaveOccurred())
+				Expect(string(body)).To(Equal(fmt.Sprintf("/%d", i)))
+			}
+			Expect(atomic.LoadInt32(&tracker.peak)).To(Equal(int32(4)))
+		})
+
+		It("should cancel the remaining requests on the first error", func() {
+			tracker := &concurrencyTracker{delay: 5 * time.Millisecond, fail: "/2"}
+			client := gorest.NewClient(gorest.WithTransport(tracker))
+			responses, err := client.DoGroup(context.Background(), &gorest.GroupOptions{Concurrency: 2}, requests(50)...)
+			Expect(err).To(MatchError(ContainSubstring("boom")))
+			Expect(responses).To(HaveLen(50))
+			Expect(responses[0]).NotTo(BeNil())
+			Expect(responses[2]).To(BeNil())
+			Expect(atomic.LoadInt32(&tracker.calls)).To(BeNumerically("<", 10))
+		})
+	})
+})
@@ -0,0 +1,427 @@
+package gorest
+
+import (
+	"bytes"
+	"context"
+	"encoding/json"
+	"io"
+	"net/http"
+	"strconv"
+	"strings"
+	"sync"
+	"time"
+)
+
+// CacheStatusHeader is set on responses returned by the CacheMiddleware to HIT (served from the cache),
+// REVALIDATED (served from the cache after a 304 Not Modified), STALE (served from the cache without
+// revalidation, see stale-while-revalidate and stale-if-error) or MISS.
+const CacheStatusHeader = "X-Cache"
+
+// CacheConfig configures the CacheMiddleware.
+type CacheConfig struct {
+	// Store holds the cached responses. Defaults to a MemoryCacheStore with 1000 entries.
+	Store CacheStore
+	// Shared makes the cache behave as a shared cache: responses marked private and responses to requests
+	// with an Authorization header (unless marked public or s-maxage) are not stored, and s-maxage is honored.
+	Shared bool
+}
+
+// CacheMiddleware returns a middleware that caches GET responses following RFC 9111.
+// It honors the Cache-Control directives max-age, s-maxage, no-store, no-cache, private, public, must-revalidate,
+// stale-while-revalidate and stale-if-error, the Expires header and Vary, and revalidates stale responses
+// with If-None-Match and If-Modified-Since. Successful unsafe requests invalidate the cached response for their URL.
+// Requests that already carry conditional headers bypass the cache.
+// Note: Responses are cached by URL only, so a single variant is kept per URL. A request whose headers do not match
+// the Vary headers of the cached response is sent to the server, and its response replaces the cached one.
+func CacheMiddleware(config *CacheConfig) Middleware {
+	cfg := CacheConfig{}
+	if config != nil {
+		cfg = *config
+	}
+	if cfg.Store == nil {
+		cfg.Store = NewMemoryCacheStore(1000)
+	}
+	c := &httpCache{store: cfg.Store, shared: cfg.Shared}
+	return func(next RoundTripFunc) RoundTripFunc {
+		return func(req *http.Request) (*http.Response, error) {
+			return c.roundTrip(req, next)
+		}
+	}
+}
+
+// httpCache implements the CacheMiddleware.
+type httpCache struct {
+	store  CacheStore
+	shared bool
+	// revalidating holds the keys being revalidated in the background.
+	revalidating sync.Map
+}
+
+// cacheEntry is a stored response.
+type cacheEntry struct {
+	Status       string
+	StatusCode   int
+	Header       http.Header
+	Body         []byte
+	RequestTime  time.Time
+	ResponseTime time.Time
+	// Vary holds the values of the request headers named by the Vary response header.
+	Vary map[string][]string `json:",omitempty"`
+}
+
+// cacheKey returns the store key of req. It ignores the Vary headers, so variants of a URL replace each other.
+func cacheKey(req *http.Request) string {
+	return req.URL.String()
+}
+
+func (c *httpCache) roundTrip(req *http.Request, next RoundTripFunc) (*http.Response, error) {
+	if req.Method != http.MethodGet {
+		resp, err := next(req)
+		if err == nil && !isSafeMethod(req.Method) && resp.StatusCode < 400 {
+			c.store.Delete(cacheKey(req))
+		}
+		return resp, err
+	}
+	if req.Header.Get("If-None-Match") != "" || req.Header.Get("If-Modified-Since") != "" || req.Header.Get("Range") != "" {
+		return next(req)
+	}
+
+	reqCC := parseCacheControl(req.Header)
+	entry := c.load(req)
+	if entry == nil {
+		return c.fetch(req, next, reqCC)
+	}
+
+	now := time.Now()
+	respCC := parseCacheControl(entry.Header)
+	lifetime := entry.freshnessLifetime(c.shared)
+	age := entry.age(now)
+	reqNoCache := hasDirective(reqCC, "no-cache")
+	respNoCache := hasDirective(respCC, "no-cache")
+	mustRevalidate := respNoCache || hasDirective(respCC, "must-revalidate") || (c.shared && hasDirective(respCC, "proxy-revalidate"))
+	if maxAge, ok := directiveSeconds(reqCC, "max-age"); ok && age > maxAge {
+		reqNoCache = true
+	}
+
+	if !reqNoCache && !respNoCache && age < lifetime {
+		return entry.response(req, now, "HIT"), nil
+	}
+	staleness := age - lifetime
+	if !reqNoCache && !mustRevalidate {
+		if window, ok := directiveSeconds(respCC, "stale-while-revalidate"); ok && staleness < window {
+			resp := entry.response(req, now, "STALE")
+			c.revalidateInBackground(req, next, entry)
+			return resp, nil
+		}
+	}
+
+	resp, err := next(conditionalRequest(req, entry))
+	if err != nil || isCacheableError(resp.StatusCode) {
+		if !mustRevalidate && entry.staleIfError(reqCC, respCC, staleness) {
+			if resp != nil {
+				DrainAndClose(resp)
+			}
+			return entry.response(req, time.Now(), "STALE"), nil
+		}
+		if err != nil {
+			return nil, err
+		}
+	}
+	if resp.StatusCode == http.StatusNotModified {
+		DrainAndClose(resp)
+		c.refresh(req, entry, resp, now)
+		return entry.response(req, time.Now(), "REVALIDATED"), nil
+	}
+	return c.storeResponse(req, resp, reqCC, now)
+}
+
+// fetch sends req and stores the response if it is cacheable.
+func (c *httpCache) fetch(req *http.Request, next RoundTripFunc, reqCC map[string]string) (*http.Response, error) {
+	requestTime := time.Now()
+	resp, err := next(req)
+	if err != nil {
+		return nil, err
+	}
+	return c.storeResponse(req, resp, reqCC, requestTime)
+}
+
+// storeResponse stores resp if it is cacheable and returns it with a fresh body.
+func (c *httpCache) storeResponse(req *http.Request, resp *http.Response, reqCC map[string]string, requestTime time.Time) (*http.Response, error) {
+	if !c.cacheable(req, resp, reqCC) {
+		if resp.StatusCode < 400 {
+			c.store.Delete(cacheKey(req))
+		}
+		resp.Header.Set(CacheStatusHeader, "MISS")
+		return resp, nil
+	}
+	body, err := io.ReadAll(resp.Body)
+	_ = resp.Body.Close()
+	if err != nil {
+		return nil, err
+	}
+	entry := &cacheEntry{
+		Status:       resp.Status,
+		StatusCode:   resp.StatusCode,
+		Header:       resp.Header.Clone(),
+		Body:         body,
+		RequestTime:  requestTime,
+		ResponseTime: time.Now(),
+		Vary:         varyValues(req, resp.Header),
+	}
+	c.save(req, entry)
+
+	resp.Body = io.NopCloser(bytes.NewReader(body))
+	resp.ContentLength = int64(len(body))
+	resp.Header.Set(CacheStatusHeader, "MISS")
+	return resp, nil
+}
+
+// cacheable reports whether resp may be stored (RFC 9111 section 3).
+func (c *httpCache) cacheable(req *http.Request, resp *http.Response, reqCC map[string]string) bool {
+	respCC := parseCacheControl(resp.Header)
+	if hasDirective(reqCC, "no-store") || hasDirective(respCC, "no-store") {
+		return false
+	}
+	if strings.TrimSpace(resp.Header.Get("Vary")) == "*" {
+		return false
+	}
+	public := hasDirective(respCC, "public")
+	sMaxAge := hasDirective(respCC, "s-maxage")
+	if c.shared {
+		if hasDirective(respCC, "private") {
+			return false
+		}
+		if req.Header.Get("Authorization") != "" && !public && !sMaxAge && !hasDirective(respCC, "must-revalidate") {
+			return false
+		}
+	}
+	explicit := public || hasDirective(respCC, "max-age") || (c.shared && sMaxAge) || resp.Header.Get("Expires") != ""
+	if explicit {
+		return true
+	}
+	// Without explicit freshness, the response is only worth storing if it can be revalidated.
+	hasValidator := resp.Header.Get("ETag") != "" || resp.Header.Get("Last-Modified") != ""
+	return hasValidator && heuristicallyCacheable(resp.StatusCode)
+}
+
+// refresh updates a stored entry with the headers of a 304 response.
+func (c *httpCache) refresh(req *http.Request, entry *cacheEntry, notModified *http.Response, requestTime time.Time) {
+	for name, values := range notModified.Header {
+		if name == "Content-Length" || name == CacheStatusHeader {
+			continue
+		}
+		entry.Header[name] = values
+	}
+	entry.RequestTime = requestTime
+	entry.ResponseTime = time.Now()
+	c.save(req, entry)
+}
+
+// revalidateInBackground revalidates entry without blocking the caller, at most once at a time per key.
+func (c *httpCache) revalidateInBackground(req *http.Request, next RoundTripFunc, entry *cacheEntry) {
+	key := cacheKey(req)
+	if _, busy := c.revalidating.LoadOrStore(key, struct{}{}); busy {
+		return
+	}
+	bg := req.Clone(context.WithoutCancel(req.Context()))
+	go func() {
+		defer c.revalidating.Delete(key)
+		requestTime := time.Now()
+		resp, err := next(conditionalRequest(bg, entry))
+		if err != nil {
+			return
+		}
+		if resp.StatusCode == http.StatusNotModified {
+			DrainAndClose(resp)
+			c.refresh(bg, entry, resp, requestTime)
+			return
+		}
+		if resp, err := c.storeResponse(bg, resp, nil, requestTime); err == nil {
+			DrainAndClose(resp)
+		}
+	}()
+}
+
+func (c *httpCache) load(req *http.Request) *cacheEntry {
+	data, ok := c.store.Get(cacheKey(req))
+	if !ok {
+		return nil
+	}
+	var entry cacheEntry
+	if err := json.Unmarshal(data, &entry); err != nil {
+		return nil
+	}
+	for name, values := range entry.Vary {
+		if strings.Join(req.Header.Values(name), ", ") != strings.Join(values, ", ") {
+			return nil
+		}
+	}
+	return &entry
+}
+
+func (c *httpCache) save(req *http.Request, entry *cacheEntry) {
+	data, err := json.Marshal(entry)
+	if err != nil {
+		return
+	}
+	c.store.Set(cacheKey(req), data)
+}
+
+// conditionalRequest returns a copy of req that asks the server to revalidate entry.
+func conditionalRequest(req *http.Request, entry *cacheEntry) *http.Request {
+	cond := req.Clone(req.Context())
+	if etag := entry.Header.Get("ETag"); etag != "" {
+		cond.Header.Set("If-None-Match", etag)
+	}
+	if lastModified := entry.Header.Get("Last-Modified"); lastModified != "" {
+		cond.Header.Set("If-Modified-Since", lastModified)
+	}
+	return cond
+}
+
+// response builds a response from the entry for req.
+func (e *cacheEntry) response(req *http.Request, now time.Time, status string) *http.Response {
+	header := e.Header.Clone()
+	header.Set("Age", strconv.Itoa(int(e.age(now).Seconds())))
+	header.Set(CacheStatusHeader, status)
+	return &http.Response{
+		Status:        e.Status,
+		StatusCode:    e.StatusCode,
+		Proto:         "HTTP/1.1",
+		ProtoMajor:    1,
+		ProtoMinor:    1,
+		Header:        header,
+		Body:          io.NopCloser(bytes.NewReader(e.Body)),
+		ContentLength: int64(len(e.Body)),
+		Request:       req,
+	}
+}
+
+// date returns the Date of the stored response, or the time it was received.
+func (e *cacheEntry) date() time.Time {
+	if t, err := http.ParseTime(e.Header.Get("Date")); err == nil {
+		return t
+	}
+	return e.ResponseTime
+}
+
+// freshnessLifetime computes how long the response is fresh (RFC 9111 section 4.2.1).
+func (e *cacheEntry) freshnessLifetime(shared bool) time.Duration {
+	cc := parseCacheControl(e.Header)
+	if shared {
+		if d, ok := directiveSeconds(cc, "s-maxage"); ok {
+			return d
+		}
+	}
+	if d, ok := directiveSeconds(cc, "max-age"); ok {
+		return d
+	}
+	if expires := e.Header.Get("Expires"); expires != "" {
+		t, err := http.ParseTime(expires)
+		if err != nil {
+			// An invalid Expires means the response is already stale.
+			return 0
+		}
+		return t.Sub(e.date())
+	}
+	if lastModified, err := http.ParseTime(e.Header.Get("Last-Modified")); err == nil && heuristicallyCacheable(e.StatusCode) {
+		// Heuristic freshness: 10% of the time since the resource was last modified.
+		return e.date().Sub(lastModified) / 10
+	}
+	return 0
+}
+
+// age computes the current age of the response (RFC 9111 section 4.2.3).
+func (e *cacheEntry) age(now time.Time) time.Duration {
+	apparentAge := max(0, e.ResponseTime.Sub(e.date()))
+	ageValue, _ := strconv.Atoi(e.Header.Get("Age"))
+	correctedAge := time.Duration(ageValue)*time.Second + e.ResponseTime.Sub(e.RequestTime)
+	return max(apparentAge, correctedAge) + now.Sub(e.ResponseTime)
+}
+
+// staleIfError reports whether the entry may be served in place of an error (RFC 5861).
+func (e *cacheEntry) staleIfError(reqCC, respCC map[string]string, staleness time.Duration) bool {
+	for _, cc := range []map[string]string{reqCC, respCC} {
+		if window, ok := directiveSeconds(cc, "stale-if-error"); ok && staleness < window {
+			return true
+		}
+	}
+	return false
+}
+
+// varyValues returns the request headers selected by the Vary header of a response.
+func varyValues(req *http.Request, header http.Header) map[string][]string {
+	var values map[string][]string
+	for _, field := range header.Values("Vary") {
+		for _, name := range strings.Split(field, ",") {
+			name = http.CanonicalHeaderKey(strings.TrimSpace(name))
+			if name == "" {
+				continue
+			}
+			if values == nil {
+				values = make(map[string][]string)
+			}
+			values[name] = req.Header.Values(name)
+		}
+	}
+	return values
+}
+
+// parseCacheControl parses the Cache-Control header into lowercase directives and their unquoted values.
+func parseCacheControl(header http.Header) map[string]string {
+	directives := make(map[string]string)
+	for _, field := range header.Values("Cache-Control") {
+		for _, part := range strings.Split(field, ",") {
+			name, value, _ := strings.Cut(strings.TrimSpace(part), "=")
+			if name == "" {
+				continue
+			}
+			directives[strings.ToLower(name)] = strings.Trim(value, `"`)
+		}
+	}
+	return directives
+}
+
+func hasDirective(cc map[string]string, name string) bool {
+	_, ok := cc[name]
+	return ok
+}
+
+// directiveSeconds returns the value of a delta-seconds directive.
+func directiveSeconds(cc map[string]string, name string) (time.Duration, bool) {
+	value, ok := cc[name]
+	if !ok {
+		return 0, false
+	}
+	seconds, err := strconv.ParseInt(value, 10, 64)
+	if err != nil || seconds < 0 {
+		return 0, false
+	}
+	return time.Duration(seconds) * time.Second, true
+}
+
+// heuristicallyCacheable reports whether a status code may be cached without explicit freshness (RFC 9110 section 15.1).
+func heuristicallyCacheable(status int) bool {
+	switch status {
+	case 200, 203, 204, 300, 301, 308, 404, 405, 410, 414, 501:
+		return true
+	}
+	return false
+}
+
+// isCacheableError reports whether a status lets a stale response be served under stale-if-error.
+func isCacheableError(status int) bool {
+	switch status {
+	case 500, 502, 503, 504:
+		return true
+	}
+	return false
+}
+
+func isSafeMethod(method string) bool {
+	switch method {
+	case http.MethodGet, http.MethodHead, http.MethodOptions, http.MethodTrace:
+		return true
+	}
+	return false
+}
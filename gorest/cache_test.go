@@ -0,0 +1,222 @@
+package gorest_test
+
+import (
+	"context"
+	"fmt"
+	"net/http"
+	"net/http/httptest"
+	"sync"
+	"time"
+
+	. "github.com/onsi/ginkgo/v2"
+	. "github.com/onsi/gomega"
+
+	"gorest/gorest"
+)
+
+var _ = Describe("CacheMiddleware", func() {
+	var (
+		mu      sync.Mutex
+		hits    int
+		handler http.HandlerFunc
+		server  *httptest.Server
+		client  *gorest.Client
+	)
+
+	BeforeEach(func() {
+		hits = 0
+		server = httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+			mu.Lock()
+			hits++
+			h := handler
+			mu.Unlock()
+			h(w, r)
+		}))
+		client = gorest.NewClient(gorest.WithMiddlewares(gorest.CacheMiddleware(nil)))
+	})
+
+	AfterEach(func() {
+		server.Close()
+	})
+
+	serverHits := func() int {
+		mu.Lock()
+		defer mu.Unlock()
+		return hits
+	}
+
+	get := func(headers ...string) (string, string) {
+		req := gorest.NewRequest("GET", server.URL+"/config")
+		for i := 0; i+1 < len(headers); i += 2 {
+			req.WithHeader(headers[i], headers[i+1])
+		}
+		resp, err := client.Do(context.Background(), req)
+		Expect(err).NotTo(HaveOccurred())
+		body, err := resp.Bytes()
+		Expect(err).NotTo(HaveOccurred())
+		return string(body), resp.Header.Get(gorest.CacheStatusHeader)
+	}
+
+	getBody := func(headers ...string) string {
+		body, _ := get(headers...)
+		return body
+	}
+
+	It("should serve fresh responses from the cache", func() {
+		handler = func(w http.ResponseWriter, r *http.Request) {
+			w.Header().Set("Cache-Control", "max-age=60")
+			_, _ = fmt.Fprintf(w, "v%d", serverHits())
+		}
+		Expect(getBody()).To(Equal("v1"))
+		body, status := get()
+		Expect(body).To(Equal("v1"))
+		Expect(status).To(Equal("HIT"))
+		Expect(serverHits()).To(Equal(1))
+	})
+
+	It("should not store responses marked no-store", func() {
+		handler = func(w http.ResponseWriter, r *http.Request) {
+			w.Header().Set("Cache-Control", "no-store, max-age=60")
+			_, _ = fmt.Fprint(w, "data")
+		}
+		get()
+		_, status := get()
+		Expect(status).To(Equal("MISS"))
+		Expect(serverHits()).To(Equal(2))
+	})
+
+	It("should treat an Expires date in the past as stale", func() {
+		handler = func(w http.ResponseWriter, r *http.Request) {
+			w.Header().Set("Expires", time.Now().Add(-time.Hour).UTC().Format(http.TimeFormat))
+			_, _ = fmt.Fprint(w, "data")
+		}
+		get()
+		get()
+		Expect(serverHits()).To(Equal(2))
+	})
+
+	It("should revalidate with If-None-Match and reuse the body on 304", func() {
+		handler = func(w http.ResponseWriter, r *http.Request) {
+			w.Header().Set("Cache-Control", "no-cache")
+			w.Header().Set("ETag", `"abc"`)
+			if r.Header.Get("If-None-Match") == `"abc"` {
+				w.WriteHeader(http.StatusNotModified)
+				return
+			}
+			_, _ = fmt.Fprint(w, "payload")
+		}
+		Expect(getBody()).To(Equal("payload"))
+		body, status := get()
+		Expect(body).To(Equal("payload"))
+		Expect(status).To(Equal("REVALIDATED"))
+		Expect(serverHits()).To(Equal(2))
+	})
+
+	It("should revalidate with If-Modified-Since", func() {
+		lastModified := time.Now().Add(-time.Hour).UTC().Format(http.TimeFormat)
+		handler = func(w http.ResponseWriter, r *http.Request) {
+			w.Header().Set("Cache-Control", "max-age=0")
+			w.Header().Set("Last-Modified", lastModified)
+			if r.Header.Get("If-Modified-Since") == lastModified {
+				w.WriteHeader(http.StatusNotModified)
+				return
+			}
+			_, _ = fmt.Fprint(w, "payload")
+		}
+		get()
+		body, status := get()
+		Expect(body).To(Equal("payload"))
+		Expect(status).To(Equal("REVALIDATED"))
+	})
+
+	It("should keep separate variants per Vary header", func() {
+		handler = func(w http.ResponseWriter, r *http.Request) {
+			w.Header().Set("Cache-Control", "max-age=60")
+			w.Header().Set("Vary", "Accept-Language")
+			_, _ = fmt.Fprint(w, r.Header.Get("Accept-Language"))
+		}
+		Expect(getBody("Accept-Language", "en")).To(Equal("en"))
+		Expect(getBody("Accept-Language", "fr")).To(Equal("fr"))
+		body, status := get("Accept-Language", "fr")
+		Expect(body).To(Equal("fr"))
+		Expect(status).To(Equal("HIT"))
+		Expect(serverHits()).To(Equal(2))
+	})
+
+	It("should honor request no-cache", func() {
+		handler = func(w http.ResponseWriter, r *http.Request) {
+			w.Header().Set("Cache-Control", "max-age=60")
+			_, _ = fmt.Fprint(w, "data")
+		}
+		get()
+		get("Cache-Control", "no-cache")
+		Expect(serverHits()).To(Equal(2))
+	})
+
+	It("should serve stale responses while revalidating in the background", func() {
+		handler = func(w http.ResponseWriter, r *http.Request) {
+			w.Header().Set("Cache-Control", "max-age=0, stale-while-revalidate=60")
+			_, _ = fmt.Fprintf(w, "v%d", serverHits())
+		}
+		Expect(getBody()).To(Equal("v1"))
+		body, status := get()
+		Expect(body).To(Equal("v1"))
+		Expect(status).To(Equal("STALE"))
+		Eventually(serverHits).Should(Equal(2))
+		Eventually(func() string { return getBody() }).Should(Equal("v2"))
+	})
+
+	It("should serve stale responses on server errors with stale-if-error", func() {
+		handler = func(w http.ResponseWriter, r *http.Request) {
+			w.Header().Set("Cache-Control", "max-age=0, stale-if-error=60")
+			_, _ = fmt.Fprint(w, "good")
+		}
+		get()
+		handler = func(w http.ResponseWriter, r *http.Request) {
+			w.WriteHeader(http.StatusServiceUnavailable)
+		}
+		body, status := get()
+		Expect(body).To(Equal("good"))
+		Expect(status).To(Equal("STALE"))
+	})
+
+	It("should invalidate the cached response after a successful unsafe request", func() {
+		handler = func(w http.ResponseWriter, r *http.Request) {
+			w.Header().Set("Cache-Control", "max-age=60")
+			_, _ = fmt.Fprint(w, "data")
+		}
+		get()
+		_, err := client.Do(context.Background(), gorest.NewRequest("PUT", server.URL+"/config").WithBody([]byte("new")))
+		Expect(err).NotTo(HaveOccurred())
+		_, status := get()
+		Expect(status).To(Equal("MISS"))
+		Expect(serverHits()).To(Equal(3))
+	})
+
+	It("should not store private responses in a shared cache", func() {
+		client = gorest.NewClient(gorest.WithMiddlewares(gorest.CacheMiddleware(&gorest.CacheConfig{Shared: true})))
+		handler = func(w http.ResponseWriter, r *http.Request) {
+			w.Header().Set("Cache-Control", "private, max-age=60")
+			_, _ = fmt.Fprint(w, "data")
+		}
+		get()
+		get()
+		Expect(serverHits()).To(Equal(2))
+	})
+
+	It("should persist entries in a DiskCacheStore", func() {
+		store, err := gorest.NewDiskCacheStore(GinkgoT().TempDir())
+		Expect(err).NotTo(HaveOccurred())
+		handler = func(w http.ResponseWriter, r *http.Request) {
+			w.Header().Set("Cache-Control", "max-age=60")
+			_, _ = fmt.Fprint(w, "data")
+		}
+		client = gorest.NewClient(gorest.WithMiddlewares(gorest.CacheMiddleware(&gorest.CacheConfig{Store: store})))
+		get()
+		client = gorest.NewClient(gorest.WithMiddlewares(gorest.CacheMiddleware(&gorest.CacheConfig{Store: store})))
+		body, status := get()
+		Expect(body).To(Equal("data"))
+		Expect(status).To(Equal("HIT"))
+		Expect(serverHits()).To(Equal(1))
+	})
+})
@@ -0,0 +1,138 @@
+package gorest
+
+import (
+	"container/list"
+	"crypto/sha256"
+	"encoding/hex"
+	"os"
+	"path/filepath"
+	"sync"
+)
+
+// CacheStore stores serialized responses for the CacheMiddleware. Implementations must be safe for concurrent use.
+type CacheStore interface {
+	// Get returns the value stored under key, if any.
+	Get(key string) ([]byte, bool)
+	// Set stores value under key, replacing any previous value.
+	Set(key string, value []byte)
+	// Delete removes the value stored under key.
+	Delete(key string)
+}
+
+// MemoryCacheStore is an in-memory CacheStore that evicts the least recently used entries.
+type MemoryCacheStore struct {
+	mu         sync.Mutex
+	maxEntries int
+	order      *list.List
+	entries    map[string]*list.Element
+}
+
+type memoryCacheItem struct {
+	key   string
+	value []byte
+}
+
+// NewMemoryCacheStore creates a MemoryCacheStore holding at most maxEntries responses.
+// A maxEntries of zero or less means no limit.
+func NewMemoryCacheStore(maxEntries int) *MemoryCacheStore {
+	return &MemoryCacheStore{
+		maxEntries: maxEntries,
+		order:      list.New(),
+		entries:    make(map[string]*list.Element),
+	}
+}
+
+// Get implements CacheStore.
+func (s *MemoryCacheStore) Get(key string) ([]byte, bool) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	elem, ok := s.entries[key]
+	if !ok {
+		return nil, false
+	}
+	s.order.MoveToFront(elem)
+	return elem.Value.(*memoryCacheItem).value, true
+}
+
+// Set implements CacheStore.
+func (s *MemoryCacheStore) Set(key string, value []byte) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	if elem, ok := s.entries[key]; ok {
+		elem.Value.(*memoryCacheItem).value = value
+		s.order.MoveToFront(elem)
+		return
+	}
+	s.entries[key] = s.order.PushFront(&memoryCacheItem{key: key, value: value})
+	if s.maxEntries > 0 && s.order.Len() > s.maxEntries {
+		oldest := s.order.Back()
+		s.order.Remove(oldest)
+		delete(s.entries, oldest.Value.(*memoryCacheItem).key)
+	}
+}
+
+// Delete implements CacheStore.
+func (s *MemoryCacheStore) Delete(key string) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	if elem, ok := s.entries[key]; ok {
+		s.order.Remove(elem)
+		delete(s.entries, key)
+	}
+}
+
+// Len returns the number of stored entries.
+func (s *MemoryCacheStore) Len() int {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	return s.order.Len()
+}
+
+// DiskCacheStore is a CacheStore that keeps one file per entry in a directory.
+// Errors reading or writing files are treated as cache misses.
+type DiskCacheStore struct {
+	dir string
+}
+
+// NewDiskCacheStore creates a DiskCacheStore in dir, creating the directory if needed.
+func NewDiskCacheStore(dir string) (*DiskCacheStore, error) {
+	if err := os.MkdirAll(dir, 0o755); err != nil {
+		return nil, err
+	}
+	return &DiskCacheStore{dir: dir}, nil
+}
+
+// path returns the file used for key. Keys are hashed since URLs are not valid file names.
+func (s *DiskCacheStore) path(key string) string {
+	sum := sha256.Sum256([]byte(key))
+	return filepath.Join(s.dir, hex.EncodeToString(sum[:]))
+}
+
+// Get implements CacheStore.
+func (s *DiskCacheStore) Get(key string) ([]byte, bool) {
+	data, err := os.ReadFile(s.path(key))
+	if err != nil {
+		return nil, false
+	}
+	return data, true
+}
+
+// Set implements CacheStore. The value is written to a temporary file first so readers never see partial entries.
+func (s *DiskCacheStore) Set(key string, value []byte) {
+	f, err := os.CreateTemp(s.dir, ".tmp-*")
+	if err != nil {
+		return
+	}
+	_, err = f.Write(value)
+	if closeErr := f.Close(); err == nil {
+		err = closeErr
+	}
+	if err != nil || os.Rename(f.Name(), s.path(key)) != nil {
+		_ = os.Remove(f.Name())
+	}
+}
+
+// Delete implements CacheStore.
+func (s *DiskCacheStore) Delete(key string) {
+	_ = os.Remove(s.path(key))
+}
@@ -0,0 +1,56 @@
+package gorest_test
+
+import (
+	. "github.com/onsi/ginkgo/v2"
+	. "github.com/onsi/gomega"
+
+	"gorest/gorest"
+)
+
+var _ = Describe("CacheStore", func() {
+	Describe("MemoryCacheStore", func() {
+		It("should evict the least recently used entry", func() {
+			store := gorest.NewMemoryCacheStore(2)
+			store.Set("a", []byte("1"))
+			store.Set("b", []byte("2"))
+			_, ok := store.Get("a")
+			Expect(ok).To(BeTrue())
+			store.Set("c", []byte("3"))
+
+			Expect(store.Len()).To(Equal(2))
+			_, ok = store.Get("b")
+			Expect(ok).To(BeFalse())
+			value, ok := store.Get("a")
+			Expect(ok).To(BeTrue())
+			Expect(string(value)).To(Equal("1"))
+		})
+
+		It("should replace and delete entries", func() {
+			store := gorest.NewMemoryCacheStore(0)
+			store.Set("a", []byte("1"))
+			store.Set("a", []byte("2"))
+			value, _ := store.Get("a")
+			Expect(string(value)).To(Equal("2"))
+			store.Delete("a")
+			_, ok := store.Get("a")
+			Expect(ok).To(BeFalse())
+		})
+	})
+
+	Describe("DiskCacheStore", func() {
+		It("should store, replace and delete entries", func() {
+			store, err := gorest.NewDiskCacheStore(GinkgoT().TempDir())
+			Expect(err).NotTo(HaveOccurred())
+			_, ok := store.Get("http://example.com/a?b=c")
+			Expect(ok).To(BeFalse())
+			store.Set("http://example.com/a?b=c", []byte("1"))
+			store.Set("http://example.com/a?b=c", []byte("2"))
+			value, ok := store.Get("http://example.com/a?b=c")
+			Expect(ok).To(BeTrue())
+			Expect(string(value)).To(Equal("2"))
+			store.Delete("http://example.com/a?b=c")
+			_, ok = store.Get("http://example.com/a?b=c")
+			Expect(ok).To(BeFalse())
+		})
+	})
+})
@@ -0,0 +1,261 @@
+package gorest
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"net/http"
+	"sync"
+	"time"
+)
+
+// CircuitState is the state of a circuit breaker.
+type CircuitState int
+
+const (
+	// CircuitClosed lets requests through and counts failures.
+	CircuitClosed CircuitState = iota
+	// CircuitOpen rejects requests without sending them.
+	CircuitOpen
+	// CircuitHalfOpen lets a limited number of probe requests through to test whether the downstream recovered.
+	CircuitHalfOpen
+)
+
+// String returns the name of the state.
+func (s CircuitState) String() string {
+	switch s {
+	case CircuitClosed:
+		return "closed"
+	case CircuitOpen:
+		return "open"
+	case CircuitHalfOpen:
+		return "half-open"
+	}
+	return fmt.Sprintf("CircuitState(%d)", int(s))
+}
+
+// ErrCircuitOpen is matched (with errors.Is) by the errors returned when a circuit breaker rejects a request.
+var ErrCircuitOpen = errors.New("circuit breaker is open")
+
+// CircuitOpenError is returned by the circuit breaker middleware when it rejects a request.
+type CircuitOpenError struct {
+	// Key identifies the circuit, by default the request host.
+	Key string
+	// RetryAt is when the circuit will let a probe request through, or zero if probes are already in flight.
+	RetryAt time.Time
+}
+
+// Error implements the error interface.
+func (e *CircuitOpenError) Error() string {
+	return fmt.Sprintf("circuit breaker is open for %q", e.Key)
+}
+
+// Is reports whether target is ErrCircuitOpen.
+func (e *CircuitOpenError) Is(target error) bool {
+	return target == ErrCircuitOpen
+}
+
+// CircuitBreakerConfig configures the CircuitBreakerMiddleware.
+type CircuitBreakerConfig struct {
+	// KeyFunc selects the circuit for a request. Defaults to the request host.
+	KeyFunc func(req *http.Request) string
+	// FailureRatio is the ratio of failed requests that opens the circuit. Defaults to 0.5.
+	FailureRatio float64
+	// MinRequests is the number of requests a closed circuit must see before it can open. Defaults to 10.
+	MinRequests int
+	// Interval is the period after which a closed circuit resets its counts. Defaults to 60 seconds.
+	Interval time.Duration
+	// OpenDuration is how long an open circuit rejects requests before letting probes through. Defaults to 30 seconds.
+	OpenDuration time.Duration
+	// HalfOpenProbes is the number of probe requests that must succeed to close the circuit. Defaults to 1.
+	HalfOpenProbes int
+	// IsFailure decides whether a request failed. Defaults to transport errors and 5xx responses.
+	// Requests canceled by the caller are neither failures nor successes and are not passed to IsFailure.
+	IsFailure func(resp *http.Response, err error) bool
+	// OnStateChange is called after a circuit changes state.
+	OnStateChange func(key string, from, to CircuitState)
+}
+
+// CircuitBreakerMiddleware returns a middleware that stops sending requests to a failing downstream.
+// Each circuit (by default one per host) opens when the failure ratio reaches FailureRatio over at least
+// MinRequests requests, rejects requests with a *CircuitOpenError for OpenDuration, then lets HalfOpenProbes
+// requests through: if they all succeed the circuit closes, otherwise it opens again.
+// Place it inside RetryMiddleware so that each attempt is counted; rejected requests are not retried.
+func CircuitBreakerMiddleware(config *CircuitBreakerConfig) Middleware {
+	cfg := CircuitBreakerConfig{}
+	if config != nil {
+		cfg = *config
+	}
+	if cfg.KeyFunc == nil {
+		cfg.KeyFunc = func(req *http.Request) string { return req.URL.Host }
+	}
+	if cfg.FailureRatio <= 0 {
+		cfg.FailureRatio = 0.5
+	}
+	if cfg.MinRequests <= 0 {
+		cfg.MinRequests = 10
+	}
+	if cfg.Interval <= 0 {
+		cfg.Interval = 60 * time.Second
+	}
+	if cfg.OpenDuration <= 0 {
+		cfg.OpenDuration = 30 * time.Second
+	}
+	if cfg.HalfOpenProbes <= 0 {
+		cfg.HalfOpenProbes = 1
+	}
+	if cfg.IsFailure == nil {
+		cfg.IsFailure = defaultIsFailure
+	}
+
+	var mu sync.Mutex
+	circuits := make(map[string]*circuit)
+	get := func(key string) *circuit {
+		mu.Lock()
+		defer mu.Unlock()
+		c, ok := circuits[key]
+		if !ok {
+			c = &circuit{key: key, cfg: &cfg, windowStart: time.Now()}
+			circuits[key] = c
+		}
+		return c
+	}
+
+	return func(next RoundTripFunc) RoundTripFunc {
+		return func(req *http.Request) (*http.Response, error) {
+			c := get(cfg.KeyFunc(req))
+			generation, err := c.allow(time.Now())
+			if err != nil {
+				return nil, err
+			}
+			resp, err := next(req)
+			if errors.Is(err, context.Canceled) {
+				// The caller gave up, which says nothing about the health of the server.
+				c.release(generation)
+				return resp, err
+			}
+			c.record(generation, cfg.IsFailure(resp, err), time.Now())
+			return resp, err
+		}
+	}
+}
+
+func defaultIsFailure(resp *http.Response, err error) bool {
+	return err != nil || resp.StatusCode >= 500
+}
+
+// circuit holds the state of a single circuit.
+type circuit struct {
+	key string
+	cfg *CircuitBreakerConfig
+
+	mu    sync.Mutex
+	state CircuitState
+	// generation changes on every state change, so results of requests allowed in an earlier state are ignored.
+	generation  uint64
+	windowStart time.Time
+	requests    int
+	failures    int
+	openedAt    time.Time
+	// probes counts probe requests in flight; successes counts successful probes.
+	probes    int
+	successes int
+}
+
+// allow reports whether a request may be sent, returning the generation to pass to record.
+func (c *circuit) allow(now time.Time) (uint64, error) {
+	c.mu.Lock()
+	var changes []CircuitState
+	defer func() {
+		c.mu.Unlock()
+		c.notify(changes)
+	}()
+
+	switch c.state {
+	case CircuitClosed:
+		if now.Sub(c.windowStart) >= c.cfg.Interval {
+			c.resetCounts(now)
+		}
+	case CircuitOpen:
+		retryAt := c.openedAt.Add(c.cfg.OpenDuration)
+		if now.Before(retryAt) {
+			return 0, &CircuitOpenError{Key: c.key, RetryAt: retryAt}
+		}
+		changes = c.setState(CircuitHalfOpen, now)
+		fallthrough
+	case CircuitHalfOpen:
+		if c.probes+c.successes >= c.cfg.HalfOpenProbes {
+			return 0, &CircuitOpenError{Key: c.key}
+		}
+		c.probes++
+	}
+	return c.generation, nil
+}
+
+// record counts the outcome of a request allowed in the given generation.
+func (c *circuit) record(generation uint64, failed bool, now time.Time) {
+	c.mu.Lock()
+	var changes []CircuitState
+	defer func() {
+		c.mu.Unlock()
+		c.notify(changes)
+	}()
+
+	if generation != c.generation {
+		return
+	}
+	switch c.state {
+	case CircuitClosed:
+		c.requests++
+		if failed {
+			c.failures++
+		}
+		if c.requests >= c.cfg.MinRequests && float64(c.failures)/float64(c.requests) >= c.cfg.FailureRatio {
+			changes = c.setState(CircuitOpen, now)
+		}
+	case CircuitHalfOpen:
+		c.probes--
+		if failed {
+			changes = c.setState(CircuitOpen, now)
+			return
+		}
+		c.successes++
+		if c.successes >= c.cfg.HalfOpenProbes {
+			changes = c.setState(CircuitClosed, now)
+		}
+	}
+}
+
+// release frees the probe slot of a request allowed in the given generation without counting an outcome.
+func (c *circuit) release(generation uint64) {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+	if generation == c.generation && c.state == CircuitHalfOpen {
+		c.probes--
+	}
+}
+
+// setState moves the circuit to state and returns the [from, to] pair for notify. It must be called with c.mu held.
+func (c *circuit) setState(state CircuitState, now time.Time) []CircuitState {
+	from := c.state
+	c.state = state
+	c.generation++
+	c.resetCounts(now)
+	c.probes, c.successes = 0, 0
+	if state == CircuitOpen {
+		c.openedAt = now
+	}
+	return []CircuitState{from, state}
+}
+
+func (c *circuit) resetCounts(now time.Time) {
+	c.windowStart = now
+	c.requests, c.failures = 0, 0
+}
+
+// notify calls OnStateChange for a state change returned by setState.
+func (c *circuit) notify(change []CircuitState) {
+	if len(change) == 2 && c.cfg.OnStateChange != nil {
+		c.cfg.OnStateChange(c.key, change[0], change[1])
+	}
+}
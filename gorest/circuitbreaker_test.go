@@ -0,0 +1,166 @@
+package gorest_test
+
+import (
+	"context"
+	"errors"
+	"io"
+	"net/http"
+	"strings"
+	"sync/atomic"
+	"time"
+
+	. "github.com/onsi/ginkgo/v2"
+	. "github.com/onsi/gomega"
+
+	"gorest/gorest"
+)
+
+var _ = Describe("CircuitBreakerMiddleware", func() {
+	var (
+		calls  int32
+		status int32
+		next   gorest.RoundTripFunc
+	)
+
+	BeforeEach(func() {
+		atomic.StoreInt32(&calls, 0)
+		atomic.StoreInt32(&status, 500)
+		next = func(req *http.Request) (*http.Response, error) {
+			atomic.AddInt32(&calls, 1)
+			return &http.Response{
+				StatusCode: int(atomic.LoadInt32(&status)),
+				Header:     http.Header{},
+				Body:       io.NopCloser(strings.NewReader("")),
+			}, nil
+		}
+	})
+
+	send := func(rt gorest.RoundTripFunc, url string) error {
+		req, err := http.NewRequest("GET", url, nil)
+		Expect(err).NotTo(HaveOccurred())
+		_, err = rt(req)
+		return err
+	}
+
+	It("should open after the failure ratio is reached and reject without calling next", func() {
+		rt := gorest.CircuitBreakerMiddleware(&gorest.CircuitBreakerConfig{MinRequests: 4, FailureRatio: 0.5})(next)
+		for i := 0; i < 4; i++ {
+			Expect(send(rt, "http://example.com")).To(Succeed())
+		}
+		err := send(rt, "http://example.com")
+		Expect(errors.Is(err, gorest.ErrCircuitOpen)).To(BeTrue())
+		var openErr *gorest.CircuitOpenError
+		Expect(errors.As(err, &openErr)).To(BeTrue())
+		Expect(openErr.Key).To(Equal("example.com"))
+		Expect(openErr.RetryAt).To(BeTemporally(">", time.Now()))
+		Expect(atomic.LoadInt32(&calls)).To(Equal(int32(4)))
+	})
+
+	It("should stay closed below the minimum number of requests", func() {
+		rt := gorest.CircuitBreakerMiddleware(&gorest.CircuitBreakerConfig{MinRequests: 10})(next)
+		for i := 0; i < 9; i++ {
+			Expect(send(rt, "http://example.com")).To(Succeed())
+		}
+		Expect(atomic.LoadInt32(&calls)).To(Equal(int32(9)))
+	})
+
+	It("should keep separate circuits per host", func() {
+		rt := gorest.CircuitBreakerMiddleware(&gorest.CircuitBreakerConfig{MinRequests: 1})(next)
+		Expect(send(rt, "http://a.example.com")).To(Succeed())
+		Expect(errors.Is(send(rt, "http://a.example.com"), gorest.ErrCircuitOpen)).To(BeTrue())
+		Expect(send(rt, "http://b.example.com")).To(Succeed())
+	})
+
+	It("should close after successful probes and report state changes", func() {
+		var changes []string
+		rt := gorest.CircuitBreakerMiddleware(&gorest.CircuitBreakerConfig{
+			MinRequests:    1,
+			OpenDuration:   20 * time.Millisecond,
+			HalfOpenProbes: 2,
+			OnStateChange: func(key string, from, to gorest.CircuitState) {
+				changes = append(changes, key+": "+from.String()+" -> "+to.String())
+			},
+		})(next)
+		Expect(send(rt, "http://example.com")).To(Succeed())
+		Expect(errors.Is(send(rt, "http://example.com"), gorest.ErrCircuitOpen)).To(BeTrue())
+
+		time.Sleep(30 * time.Millisecond)
+		atomic.StoreInt32(&status, 200)
+		Expect(send(rt, "http://example.com")).To(Succeed())
+		Expect(send(rt, "http://example.com")).To(Succeed())
+		Expect(send(rt, "http://example.com")).To(Succeed())
+		Expect(changes).To(Equal([]string{
+			"example.com: closed -> open",
+			"example.com: open -> half-open",
+			"example.com: half-open -> closed",
+		}))
+	})
+
+	It("should reopen when a probe fails", func() {
+		var last gorest.CircuitState
+		rt := gorest.CircuitBreakerMiddleware(&gorest.CircuitBreakerConfig{
+			MinRequests:   1,
+			OpenDuration:  20 * time.Millisecond,
+			OnStateChange: func(_ string, _, to gorest.CircuitState) { last = to },
+		})(next)
+		Expect(send(rt, "http://example.com")).To(Succeed())
+		time.Sleep(30 * time.Millisecond)
+		Expect(send(rt, "http://example.com")).To(Succeed())
+		Expect(last).To(Equal(gorest.CircuitOpen))
+		Expect(errors.Is(send(rt, "http://example.com"), gorest.ErrCircuitOpen)).To(BeTrue())
+		Expect(atomic.LoadInt32(&calls)).To(Equal(int32(2)))
+	})
+
+	It("should not count canceled requests", func() {
+		var changes []string
+		var canceled atomic.Bool
+		failing := func(req *http.Request) (*http.Response, error) {
+			if canceled.Load() {
+				return nil, context.Canceled
+			}
+			return nil, errors.New("connection refused")
+		}
+		rt := gorest.CircuitBreakerMiddleware(&gorest.CircuitBreakerConfig{
+			MinRequests:  1,
+			OpenDuration: 20 * time.Millisecond,
+			OnStateChange: func(_ string, from, to gorest.CircuitState) {
+				changes = append(changes, from.String()+" -> "+to.String())
+			},
+		})(failing)
+
+		canceled.Store(true)
+		Expect(send(rt, "http://example.com")).To(MatchError(context.Canceled))
+		Expect(changes).To(BeEmpty())
+
+		canceled.Store(false)
+		Expect(send(rt, "http://example.com")).To(HaveOccurred())
+		time.Sleep(30 * time.Millisecond)
+		canceled.Store(true)
+		Expect(send(rt, "http://example.com")).To(MatchError(context.Canceled))
+		Expect(changes).To(Equal([]string{"closed -> open", "open -> half-open"}))
+
+		// The canceled probe freed its slot, so the next request probes again.
+		canceled.Store(false)
+		err := send(rt, "http://example.com")
+		Expect(errors.Is(err, gorest.ErrCircuitOpen)).To(BeFalse())
+		Expect(changes).To(Equal([]string{"closed -> open", "open -> half-open", "half-open -> open"}))
+	})
+
+	It("should use the key function", func() {
+		rt := gorest.CircuitBreakerMiddleware(&gorest.CircuitBreakerConfig{
+			MinRequests: 1,
+			KeyFunc:     func(*http.Request) string { return "shared" },
+		})(next)
+		Expect(send(rt, "http://a.example.com")).To(Succeed())
+		Expect(errors.Is(send(rt, "http://b.example.com"), gorest.ErrCircuitOpen)).To(BeTrue())
+	})
+
+	It("should not be retried by the retry middleware", func() {
+		breaker := gorest.CircuitBreakerMiddleware(&gorest.CircuitBreakerConfig{MinRequests: 1})
+		retry := gorest.RetryMiddlewareWithConfig(&gorest.RetryConfig{Attempts: 5, Backoff: gorest.ConstantBackoff(0)})
+		rt := retry(breaker(next))
+		err := send(rt, "http://example.com")
+		Expect(errors.Is(err, gorest.ErrCircuitOpen)).To(BeTrue())
+		Expect(atomic.LoadInt32(&calls)).To(Equal(int32(1)))
+	})
+})
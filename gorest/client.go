@@ -5,9 +5,15 @@ import (
 	"context"
 	"io"
 	"net/http"
+	"sync"
+	"sync/atomic"
 	"time"
 )
 
+// defaultGroupConcurrency is the number of workers used by DoGroup and DoGroupAsync
+// when the client has no WithMaxConcurrency limit.
+const defaultGroupConcurrency = 16
+
 // Client is a configurable API client that supports middleware chaining and request building.
 type Client struct {
 	client      *http.Client
@@ -16,6 +22,14 @@ type Client struct {
 	timeout     time.Duration
 	// autoBuffer controls whether non-streaming responses are fully read into memory.
 	autoBuffer bool
+	// baseURL is used to resolve relative request URLs.
+	baseURL string
+	// codecs encode and decode bodies by content type.
+	codecs codecRegistry
+	// statusErrors controls whether non-2xx responses are returned as *HTTPError.
+	statusErrors bool
+	// maxConcurrency limits the number of requests in flight; zero means no limit.
+	maxConcurrency int
 }
 
 // Option defines a function signature for configuring the Client.
@@ -29,6 +43,7 @@ func NewClient(options ...Option) *Client {
 		middlewares: []Middleware{},
 		timeout:     30 * time.Second,
 		autoBuffer:  true,
+		codecs:      newCodecRegistry(JSONCodec{}, XMLCodec{}, FormCodec{}),
 	}
 	for _, opt := range options {
 		opt(c)
@@ -59,9 +74,17 @@ func NewClient(options ...Option) *Client {
 }
 
 func (c *Client) wrapTransport(base http.RoundTripper) http.RoundTripper {
+	var bulkhead Middleware
+	if c.maxConcurrency > 0 {
+		bulkhead = BulkheadMiddleware(c.maxConcurrency)
+	}
 	return RoundTripFunc(func(req *http.Request) (*http.Response, error) {
-		final := func(req *http.Request) (*http.Response, error) {
+		final := RoundTripFunc(func(req *http.Request) (*http.Response, error) {
 			return base.RoundTrip(req)
+		})
+		if bulkhead != nil {
+			// The limit applies to requests sent on the wire, so each retry attempt takes a slot.
+			final = bulkhead(final)
 		}
 		chain := ChainMiddlewares(final, c.middlewares...)
 		return chain(req)
@@ -89,6 +112,23 @@ func WithTimeout(timeout time.Duration) Option {
 	}
 }
 
+// WithBaseURL sets the base URL that relative request URLs (e.g. "/users/{id}") are resolved against.
+func WithBaseURL(baseURL string) Option {
+	return func(c *Client) {
+		c.baseURL = baseURL
+	}
+}
+
+// WithCodecs registers codecs used to encode bodies set with Request.WithEncodedBody
+// and to decode responses with Response.Decode. A codec replaces any codec for the same content type.
+func WithCodecs(codecs ...Codec) Option {
+	return func(c *Client) {
+		for _, codec := range codecs {
+			c.codecs.register(codec)
+		}
+	}
+}
+
 // WithMiddlewares adds one or more middleware functions to the client.
 func WithMiddlewares(mws ...Middleware) Option {
 	return func(c *Client) {
@@ -104,16 +144,65 @@ func WithAutoBufferResponse(autoBuffer bool) Option {
 	}
 }
 
+// WithMaxConcurrency limits the number of requests the client has in flight at once, waiting for a free slot
+// before sending more (see BulkheadMiddleware). It also sets the number of workers used by DoGroup and DoGroupAsync.
+// A request holds its slot until its response body is closed, which Do does when it buffers the response.
+func WithMaxConcurrency(n int) Option {
+	return func(c *Client) {
+		c.maxConcurrency = n
+	}
+}
+
+// WithStatusErrors configures whether Do and DoStream turn 4xx and 5xx responses into an *HTTPError.
+// When enabled, the response body is consumed and the error is returned instead of a Response.
+// Defaults to false.
+func WithStatusErrors(enabled bool) Option {
+	return func(c *Client) {
+		c.statusErrors = enabled
+	}
+}
+
+// roundTrip builds the HTTP request and sends it through the underlying http.Client.
+// The returned response is not checked for its status code.
+func (c *Client) roundTrip(ctx context.Context, req *Request) (*http.Response, error) {
+	httpReq, err := req.buildHTTPRequest(c.baseURL, c.codecs)
+	if err != nil {
+		return nil, err
+	}
+	return c.send(ctx, req, httpReq)
+}
+
+// send sends httpReq, built from req, through the middleware chain and transport.
+func (c *Client) send(ctx context.Context, req *Request, httpReq *http.Request) (*http.Response, error) {
+	ctx, _ = withAttemptCounter(withoutAttemptHooks(ctx))
+	httpReq = httpReq.WithContext(withURLTemplate(ctx, req))
+	resp, err := c.client.Do(httpReq)
+	if err != nil {
+		return nil, err
+	}
+	if resp.Request == nil {
+		// Custom round trippers may not link the response to its request.
+		resp.Request = httpReq
+	}
+	return resp, nil
+}
+
+// checkStatus returns an *HTTPError for non-2xx responses if the client is configured with WithStatusErrors.
+func (c *Client) checkStatus(resp *http.Response) error {
+	if !c.statusErrors || (resp.StatusCode >= 200 && resp.StatusCode < 300) {
+		return nil
+	}
+	return newHTTPError(resp.Request, resp)
+}
+
 // Do sends the HTTP request built from the provided Request and returns a Response.
 // For non-streaming requests, the full response is read into memory (if autoBuffer is true).
 func (c *Client) Do(ctx context.Context, req *Request) (res *Response, err error) {
-	httpReq, err := req.BuildHTTPRequest()
+	resp, err := c.roundTrip(ctx, req)
 	if err != nil {
 		return nil, err
 	}
-	httpReq = httpReq.WithContext(ctx)
-	resp, err := c.client.Do(httpReq)
-	if err != nil {
+	if err := c.checkStatus(resp); err != nil {
 		return nil, err
 	}
 	if c.autoBuffer {
@@ -128,14 +217,16 @@ func (c *Client) Do(ctx context.Context, req *Request) (res *Response, err error
 			return nil, err
 		}
 		return &Response{Response: &http.Response{
-			Status:     resp.Status,
-			StatusCode: resp.StatusCode,
-			Header:     resp.Header,
-			Body:       io.NopCloser(bytes.NewReader(body)),
-		}}, nil
+			Status:        resp.Status,
+			StatusCode:    resp.StatusCode,
+			Header:        resp.Header,
+			Body:          io.NopCloser(bytes.NewReader(body)),
+			ContentLength: int64(len(body)),
+			Request:       resp.Request,
+		}, codecs: c.codecs}, nil
 	}
 	// If autoBuffer is disabled, return the raw response.
-	return &Response{Response: resp}, nil
+	return &Response{Response: resp, codecs: c.codecs}, nil
 }
 
 // DoAsync sends the HTTP request asynchronously. It launches a goroutine
@@ -154,17 +245,15 @@ func (c *Client) DoAsync(ctx context.Context, req *Request) <-chan AsyncResponse
 // DoStream sends the HTTP request built from the provided Request and returns a Response
 // for manual streaming. The caller is responsible for closing the response.
 func (c *Client) DoStream(ctx context.Context, req *Request) (*Response, error) {
-	httpReq, err := req.BuildHTTPRequest()
+	resp, err := c.roundTrip(ctx, req)
 	if err != nil {
 		return nil, err
 	}
-	httpReq = httpReq.WithContext(ctx)
-	resp, err := c.client.Do(httpReq)
-	if err != nil {
+	if err := c.checkStatus(resp); err != nil {
 		return nil, err
 	}
 	// The caller should use methods like StreamChunks() to process the response.
-	return &Response{Response: resp}, nil
+	return &Response{Response: resp, codecs: c.codecs}, nil
 }
 
 // DoStreamAsync is similar to DoAsync but uses the DoStream method to allow manual streaming.
@@ -177,17 +266,86 @@ func (c *Client) DoStreamAsync(ctx context.Context, req *Request) <-chan AsyncRe
 	return responseChan
 }
 
-// DoGroupAsync fires off multiple asynchronous HTTP requests concurrently,
-// one for each provided *Request, and returns a channel that will eventually
-// yield a slice of AsyncResult (one per request).
+// DoGroupAsync sends multiple requests concurrently, using at most the client's WithMaxConcurrency limit
+// (or 16) workers, and returns a channel that will eventually yield a slice of AsyncResponse in request order.
+// Unlike DoGroup, a failed request does not cancel the others.
 func (c *Client) DoGroupAsync(ctx context.Context, requests ...*Request) <-chan []AsyncResponse {
-	// Create a slice of async result channels—one per request.
-	channels := make([]<-chan AsyncResponse, len(requests))
-	for i, req := range requests {
-		channels[i] = c.DoAsync(ctx, req)
+	out := make(chan []AsyncResponse, 1)
+	go func() {
+		results := make([]AsyncResponse, len(requests))
+		runWorkers(len(requests), c.groupConcurrency(), func(i int) {
+			res, err := c.Do(ctx, requests[i])
+			results[i] = AsyncResponse{Response: res, Error: err}
+		})
+		out <- results
+	}()
+	return out
+}
+
+// GroupOptions configures Client.DoGroup.
+type GroupOptions struct {
+	// Concurrency is the maximum number of requests sent at once.
+	// Defaults to the client's WithMaxConcurrency limit, or 16.
+	Concurrency int
+}
+
+// DoGroup sends the requests with Do using a bounded number of workers and returns the responses in request order.
+// The first error cancels the context of the requests still running, stops the remaining ones from being sent,
+// and is returned along with the responses received so far (nil for requests that failed or were not sent).
+func (c *Client) DoGroup(ctx context.Context, opts *GroupOptions, requests ...*Request) ([]*Response, error) {
+	limit := c.groupConcurrency()
+	if opts != nil && opts.Concurrency > 0 {
+		limit = opts.Concurrency
+	}
+	ctx, cancel := context.WithCancel(ctx)
+	defer cancel()
+
+	responses := make([]*Response, len(requests))
+	var once sync.Once
+	var firstErr error
+	runWorkers(len(requests), limit, func(i int) {
+		if ctx.Err() != nil {
+			once.Do(func() { firstErr = ctx.Err() })
+			return
+		}
+		res, err := c.Do(ctx, requests[i])
+		if err != nil {
+			once.Do(func() {
+				firstErr = err
+				cancel()
+			})
+			return
+		}
+		responses[i] = res
+	})
+	return responses, firstErr
+}
+
+func (c *Client) groupConcurrency() int {
+	if c.maxConcurrency > 0 {
+		return c.maxConcurrency
+	}
+	return defaultGroupConcurrency
+}
+
+// runWorkers calls fn for each index in [0, n) from at most limit goroutines and waits for them to finish.
+func runWorkers(n, limit int, fn func(i int)) {
+	var next atomic.Int64
+	var wg sync.WaitGroup
+	for range min(n, limit) {
+		wg.Add(1)
+		go func() {
+			defer wg.Done()
+			for {
+				i := int(next.Add(1) - 1)
+				if i >= n {
+					return
+				}
+				fn(i)
+			}
+		}()
 	}
-	// Use the join helper to aggregate all results.
-	return c.JoinAsyncResponses(channels...)
+	wg.Wait()
 }
 
 // JoinAsyncResponses accepts multiple AsyncResult channels and returns a channel that will emit
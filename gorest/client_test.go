@@ -135,6 +135,16 @@ var _ = Describe("Client", func() {
 		Expect(err).To(HaveOccurred())
 	})
 
+	It("should resolve relative request URLs against the client base URL", func() {
+		client := gorest.NewClient(gorest.WithBaseURL(testServer.URL))
+		req := gorest.NewRequest("GET", "/{kind}").WithPathParam("kind", "text")
+		resp, err := client.Do(context.Background(), req)
+		Expect(err).NotTo(HaveOccurred())
+		body, err := resp.Bytes()
+		Expect(err).NotTo(HaveOccurred())
+		Expect(string(body)).To(Equal("Hello, World!"))
+	})
+
 	It("should use a custom HTTP client provided via WithHTTPClient", func() {
 		// Create a dummy RoundTrip function.
 		rt := gorest.RoundTripFunc(func(req *http.Request) (*http.Response, error) {
@@ -0,0 +1,132 @@
+package gorest
+
+import (
+	"encoding/json"
+	"encoding/xml"
+	"fmt"
+	"mime"
+	"net/url"
+	"strings"
+)
+
+// Codec encodes and decodes request and response bodies for a content type.
+type Codec interface {
+	// ContentType returns the media type handled by the codec, e.g. "application/json".
+	ContentType() string
+	// Marshal encodes v.
+	Marshal(v interface{}) ([]byte, error)
+	// Unmarshal decodes data into v.
+	Unmarshal(data []byte, v interface{}) error
+}
+
+// JSONCodec encodes and decodes application/json bodies with encoding/json.
+type JSONCodec struct{}
+
+// ContentType implements Codec.
+func (JSONCodec) ContentType() string { return "application/json" }
+
+// Marshal implements Codec.
+func (JSONCodec) Marshal(v interface{}) ([]byte, error) { return json.Marshal(v) }
+
+// Unmarshal implements Codec.
+func (JSONCodec) Unmarshal(data []byte, v interface{}) error { return json.Unmarshal(data, v) }
+
+// XMLCodec encodes and decodes application/xml bodies with encoding/xml.
+type XMLCodec struct{}
+
+// ContentType implements Codec.
+func (XMLCodec) ContentType() string { return "application/xml" }
+
+// Marshal implements Codec.
+func (XMLCodec) Marshal(v interface{}) ([]byte, error) { return xml.Marshal(v) }
+
+// Unmarshal implements Codec.
+func (XMLCodec) Unmarshal(data []byte, v interface{}) error { return xml.Unmarshal(data, v) }
+
+// FormCodec encodes and decodes application/x-www-form-urlencoded bodies.
+// It marshals url.Values, map[string]string and map[string][]string, and unmarshals into pointers to those types.
+type FormCodec struct{}
+
+// ContentType implements Codec.
+func (FormCodec) ContentType() string { return "application/x-www-form-urlencoded" }
+
+// Marshal implements Codec.
+func (FormCodec) Marshal(v interface{}) ([]byte, error) {
+	switch values := v.(type) {
+	case url.Values:
+		return []byte(values.Encode()), nil
+	case map[string][]string:
+		return []byte(url.Values(values).Encode()), nil
+	case map[string]string:
+		form := url.Values{}
+		for k, val := range values {
+			form.Set(k, val)
+		}
+		return []byte(form.Encode()), nil
+	}
+	return nil, fmt.Errorf("form codec: unsupported type %T", v)
+}
+
+// Unmarshal implements Codec.
+func (FormCodec) Unmarshal(data []byte, v interface{}) error {
+	form, err := url.ParseQuery(string(data))
+	if err != nil {
+		return err
+	}
+	switch target := v.(type) {
+	case *url.Values:
+		*target = form
+	case *map[string][]string:
+		*target = form
+	case *map[string]string:
+		m := make(map[string]string, len(form))
+		for k := range form {
+			m[k] = form.Get(k)
+		}
+		*target = m
+	default:
+		return fmt.Errorf("form codec: unsupported type %T", v)
+	}
+	return nil
+}
+
+// codecRegistry maps media types to codecs.
+type codecRegistry map[string]Codec
+
+// defaultCodecs holds the built-in codecs used when a Client has no codec for a content type.
+var defaultCodecs = newCodecRegistry(JSONCodec{}, XMLCodec{}, FormCodec{})
+
+func newCodecRegistry(codecs ...Codec) codecRegistry {
+	registry := codecRegistry{}
+	for _, codec := range codecs {
+		registry.register(codec)
+	}
+	return registry
+}
+
+func (r codecRegistry) register(codec Codec) {
+	r[strings.ToLower(codec.ContentType())] = codec
+}
+
+// lookup returns the codec for contentType. Parameters such as charset are ignored, and structured
+// syntax suffixes ("application/problem+json") and text/xml fall back to the JSON and XML codecs.
+func (r codecRegistry) lookup(contentType string) (Codec, error) {
+	mediaType, _, err := mime.ParseMediaType(contentType)
+	if err != nil {
+		return nil, fmt.Errorf("invalid content type %q: %w", contentType, err)
+	}
+	if codec, ok := r[mediaType]; ok {
+		return codec, nil
+	}
+	var fallback string
+	switch {
+	case strings.HasSuffix(mediaType, "+json"):
+		fallback = "application/json"
+	case strings.HasSuffix(mediaType, "+xml") || mediaType == "text/xml":
+		fallback = "application/xml"
+	}
+	if codec, ok := r[fallback]; ok {
+		return codec, nil
+	}
+	return nil, fmt.Errorf("no codec registered for content type %q", mediaType)
+}
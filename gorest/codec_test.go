@@ -0,0 +1,116 @@
+package gorest_test
+
+import (
+	"context"
+	"encoding/xml"
+	"fmt"
+	"io"
+	"net/http"
+	"net/http/httptest"
+	"net/url"
+	"strings"
+
+	. "github.com/onsi/ginkgo/v2"
+	. "github.com/onsi/gomega"
+
+	"gorest/gorest"
+)
+
+type xmlOrder struct {
+	XMLName xml.Name `xml:"order"`
+	ID      int      `xml:"id"`
+	Item    string   `xml:"item"`
+}
+
+// upperCodec is a toy codec for text/x-upper that upper-cases strings.
+type upperCodec struct{}
+
+func (upperCodec) ContentType() string { return "text/x-upper" }
+
+func (upperCodec) Marshal(v interface{}) ([]byte, error) {
+	return []byte(strings.ToUpper(v.(string))), nil
+}
+
+func (upperCodec) Unmarshal(data []byte, v interface{}) error {
+	*(v.(*string)) = strings.ToLower(string(data))
+	return nil
+}
+
+var _ = Describe("Codecs", func() {
+	newResponse := func(contentType, body string) *gorest.Response {
+		return &gorest.Response{Response: &http.Response{
+			StatusCode: 200,
+			Header:     http.Header{"Content-Type": {contentType}},
+			Body:       io.NopCloser(strings.NewReader(body)),
+		}}
+	}
+
+	It("should decode XML responses", func() {
+		var order xmlOrder
+		err := newResponse("application/xml; charset=utf-8", "<order><id>3</id><item>tea</item></order>").Decode(&order)
+		Expect(err).NotTo(HaveOccurred())
+		Expect(order.ID).To(Equal(3))
+		Expect(order.Item).To(Equal("tea"))
+	})
+
+	It("should decode form-encoded responses", func() {
+		var form url.Values
+		err := newResponse("application/x-www-form-urlencoded", "a=1&b=two").Decode(&form)
+		Expect(err).NotTo(HaveOccurred())
+		Expect(form.Get("b")).To(Equal("two"))
+	})
+
+	It("should fall back to JSON for structured syntax suffixes and missing content types", func() {
+		var problem map[string]string
+		Expect(newResponse("application/problem+json", `{"title": "bad"}`).Decode(&problem)).To(Succeed())
+		Expect(problem["title"]).To(Equal("bad"))
+
+		response := newResponse("", `{"title": "untyped"}`)
+		response.Header.Del("Content-Type")
+		Expect(response.Decode(&problem)).To(Succeed())
+		Expect(problem["title"]).To(Equal("untyped"))
+	})
+
+	It("should return an error for unsupported content types", func() {
+		var v string
+		err := newResponse("image/png", "data").Decode(&v)
+		Expect(err).To(MatchError(ContainSubstring("no codec registered")))
+	})
+
+	It("should build XML and form request bodies", func() {
+		httpReq, err := gorest.NewRequest("POST", "http://example.com").WithXMLBody(xmlOrder{ID: 1, Item: "tea"}).BuildHTTPRequest()
+		Expect(err).NotTo(HaveOccurred())
+		Expect(httpReq.Header.Get("Content-Type")).To(Equal("application/xml"))
+		body, _ := io.ReadAll(httpReq.Body)
+		Expect(string(body)).To(Equal("<order><id>1</id><item>tea</item></order>"))
+
+		httpReq, err = gorest.NewRequest("POST", "http://example.com").WithFormBody(url.Values{"q": {"a b"}}).BuildHTTPRequest()
+		Expect(err).NotTo(HaveOccurred())
+		Expect(httpReq.Header.Get("Content-Type")).To(Equal("application/x-www-form-urlencoded"))
+		body, _ = io.ReadAll(httpReq.Body)
+		Expect(string(body)).To(Equal("q=a+b"))
+	})
+
+	It("should encode and decode with codecs registered on the client", func() {
+		server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+			b, _ := io.ReadAll(r.Body)
+			w.Header().Set("Content-Type", r.Header.Get("Content-Type"))
+			_, _ = fmt.Fprint(w, string(b))
+		}))
+		defer server.Close()
+
+		client := gorest.NewClient(gorest.WithCodecs(upperCodec{}))
+		req := gorest.NewRequest("POST", server.URL).WithEncodedBody("text/x-upper", "hello")
+		resp, err := client.Do(context.Background(), req)
+		Expect(err).NotTo(HaveOccurred())
+		Expect(resp.Header.Get("Content-Type")).To(Equal("text/x-upper"))
+		var out string
+		Expect(resp.Decode(&out)).To(Succeed())
+		Expect(out).To(Equal("hello"))
+	})
+
+	It("should fail to build encoded bodies without a matching codec", func() {
+		_, err := gorest.NewRequest("POST", "http://example.com").WithEncodedBody("text/x-upper", "hello").BuildHTTPRequest()
+		Expect(err).To(MatchError(ContainSubstring("no codec registered")))
+	})
+})
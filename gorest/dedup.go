@@ -0,0 +1,131 @@
+package gorest
+
+import (
+	"bytes"
+	"context"
+	"io"
+	"net/http"
+	"slices"
+	"strings"
+	"sync"
+)
+
+// DedupConfig configures the DedupMiddleware.
+type DedupConfig struct {
+	// Methods lists the methods whose requests are deduplicated. Defaults to GET and HEAD.
+	Methods []string
+	// Headers lists the request headers that are part of the key, in addition to the method and URL.
+	// Defaults to Accept, Authorization and Cookie, so that requests for different representations
+	// or on behalf of different users are never collapsed.
+	Headers []string
+}
+
+// DedupMiddleware returns a middleware that collapses concurrent identical requests into a single round trip.
+// Requests with the same method, URL and selected headers that arrive while one is in flight wait for it,
+// and every caller receives its own copy of the buffered response.
+// The shared round trip is cancelled only once every waiting caller has given up.
+func DedupMiddleware(config *DedupConfig) Middleware {
+	cfg := DedupConfig{}
+	if config != nil {
+		cfg = *config
+	}
+	if cfg.Methods == nil {
+		cfg.Methods = []string{http.MethodGet, http.MethodHead}
+	}
+	if cfg.Headers == nil {
+		cfg.Headers = []string{"Accept", "Authorization", "Cookie"}
+	}
+	key := func(req *http.Request) string {
+		var b strings.Builder
+		b.WriteString(req.Method + " " + req.URL.String())
+		for _, name := range cfg.Headers {
+			b.WriteString("\n" + name + ": " + strings.Join(req.Header.Values(name), ", "))
+		}
+		return b.String()
+	}
+
+	var mu sync.Mutex
+	flights := make(map[string]*flight)
+	return func(next RoundTripFunc) RoundTripFunc {
+		return func(req *http.Request) (*http.Response, error) {
+			if !slices.Contains(cfg.Methods, req.Method) {
+				return next(req)
+			}
+			k := key(req)
+
+			mu.Lock()
+			f, ok := flights[k]
+			if ok {
+				f.waiters++
+			} else {
+				// The shared round trip must outlive the caller that started it, as long as others are waiting.
+				ctx, cancel := context.WithCancel(context.WithoutCancel(req.Context()))
+				f = &flight{done: make(chan struct{}), waiters: 1, cancel: cancel}
+				flights[k] = f
+				go func() {
+					defer cancel()
+					f.run(next, req.WithContext(ctx))
+					mu.Lock()
+					if flights[k] == f {
+						delete(flights, k)
+					}
+					mu.Unlock()
+					close(f.done)
+				}()
+			}
+			mu.Unlock()
+
+			select {
+			case <-f.done:
+				if f.err != nil {
+					return nil, f.err
+				}
+				return f.response(req), nil
+			case <-req.Context().Done():
+				mu.Lock()
+				f.waiters--
+				if f.waiters == 0 {
+					f.cancel()
+					if flights[k] == f {
+						delete(flights, k)
+					}
+				}
+				mu.Unlock()
+				return nil, req.Context().Err()
+			}
+		}
+	}
+}
+
+// flight is a round trip shared by deduplicated requests.
+type flight struct {
+	done    chan struct{}
+	waiters int
+	cancel  context.CancelFunc
+	resp    *http.Response
+	body    []byte
+	err     error
+}
+
+// run sends req and buffers the response.
+func (f *flight) run(next RoundTripFunc, req *http.Request) {
+	resp, err := next(req)
+	if err != nil {
+		f.err = err
+		return
+	}
+	defer resp.Body.Close()
+	f.body, f.err = io.ReadAll(resp.Body)
+	f.resp = resp
+}
+
+// response returns a copy of the shared response with its own body reader.
+func (f *flight) response(req *http.Request) *http.Response {
+	resp := *f.resp
+	resp.Header = f.resp.Header.Clone()
+	resp.Trailer = f.resp.Trailer.Clone()
+	resp.Body = io.NopCloser(bytes.NewReader(f.body))
+	resp.ContentLength = int64(len(f.body))
+	resp.Request = req
+	return &resp
+}
@@ -0,0 +1,151 @@
+package gorest_test
+
+import (
+	"context"
+	"io"
+	"net/http"
+	"strings"
+	"sync"
+	"sync/atomic"
+	"time"
+
+	. "github.com/onsi/ginkgo/v2"
+	. "github.com/onsi/gomega"
+
+	"gorest/gorest"
+)
+
+var _ = Describe("DedupMiddleware", func() {
+	var (
+		calls   int32
+		release chan struct{}
+		rt      gorest.RoundTripFunc
+	)
+
+	BeforeEach(func() {
+		atomic.StoreInt32(&calls, 0)
+		release = make(chan struct{})
+		next := func(req *http.Request) (*http.Response, error) {
+			atomic.AddInt32(&calls, 1)
+			select {
+			case <-release:
+			case <-req.Context().Done():
+				return nil, req.Context().Err()
+			}
+			return &http.Response{
+				StatusCode: 200,
+				Header:     http.Header{"Content-Type": {"text/plain"}},
+				Body:       io.NopCloser(strings.NewReader("shared body")),
+			}, nil
+		}
+		rt = gorest.DedupMiddleware(nil)(next)
+	})
+
+	// sendConcurrently sends the requests at once and returns their bodies and errors once all have finished.
+	sendConcurrently := func(reqs ...*http.Request) ([]string, []error) {
+		bodies := make([]string, len(reqs))
+		errs := make([]error, len(reqs))
+		var wg sync.WaitGroup
+		for i, req := range reqs {
+			wg.Add(1)
+			go func() {
+				defer wg.Done()
+				resp, err := rt(req)
+				if err != nil {
+					errs[i] = err
+					return
+				}
+				b, _ := io.ReadAll(resp.Body)
+				bodies[i] = string(b)
+			}()
+		}
+		time.Sleep(20 * time.Millisecond)
+		close(release)
+		wg.Wait()
+		return bodies, errs
+	}
+
+	newRequest := func(method, auth string) *http.Request {
+		req, err := http.NewRequest(method, "http://example.com/items", nil)
+		Expect(err).NotTo(HaveOccurred())
+		if auth != "" {
+			req.Header.Set("Authorization", auth)
+		}
+		return req
+	}
+
+	It("should collapse concurrent identical requests into one round trip", func() {
+		reqs := make([]*http.Request, 5)
+		for i := range reqs {
+			reqs[i] = newRequest("GET", "")
+		}
+		bodies, errs := sendConcurrently(reqs...)
+		Expect(atomic.LoadInt32(&calls)).To(Equal(int32(1)))
+		for i := range reqs {
+			Expect(errs[i]).NotTo(HaveOccurred())
+			Expect(bodies[i]).To(Equal("shared body"))
+		}
+	})
+
+	It("should keep requests with different selected headers apart", func() {
+		_, errs := sendConcurrently(newRequest("GET", "Bearer a"), newRequest("GET", "Bearer b"), newRequest("GET", "Bearer a"))
+		Expect(errs).To(HaveEach(BeNil()))
+		Expect(atomic.LoadInt32(&calls)).To(Equal(int32(2)))
+	})
+
+	It("should not deduplicate unsafe methods", func() {
+		_, errs := sendConcurrently(newRequest("POST", ""), newRequest("POST", ""))
+		Expect(errs).To(HaveEach(BeNil()))
+		Expect(atomic.LoadInt32(&calls)).To(Equal(int32(2)))
+	})
+
+	It("should keep the shared round trip running when the first caller gives up", func() {
+		ctx, cancel := context.WithCancel(context.Background())
+		first := newRequest("GET", "").WithContext(ctx)
+		second := newRequest("GET", "")
+
+		var firstErr error
+		done := make(chan struct{})
+		go func() {
+			defer close(done)
+			_, firstErr = rt(first)
+		}()
+		Eventually(func() int32 { return atomic.LoadInt32(&calls) }).Should(Equal(int32(1)))
+		cancel()
+		<-done
+		Expect(firstErr).To(MatchError(context.Canceled))
+
+		go func() {
+			time.Sleep(20 * time.Millisecond)
+			close(release)
+		}()
+		resp, err := rt(second)
+		Expect(err).NotTo(HaveOccurred())
+		body, _ := io.ReadAll(resp.Body)
+		Expect(string(body)).To(Equal("shared body"))
+		Expect(resp.Request).To(BeIdenticalTo(second))
+	})
+
+	It("should fan out responses with independent bodies through DoGroupAsync", func() {
+		var hits int32
+		client := gorest.NewClient(
+			gorest.WithMiddlewares(gorest.DedupMiddleware(nil)),
+			gorest.WithTransport(gorest.RoundTripFunc(func(req *http.Request) (*http.Response, error) {
+				atomic.AddInt32(&hits, 1)
+				time.Sleep(20 * time.Millisecond)
+				return &http.Response{StatusCode: 200, Header: http.Header{}, Body: io.NopCloser(strings.NewReader("warm"))}, nil
+			})),
+		)
+		reqs := make([]*gorest.Request, 4)
+		for i := range reqs {
+			reqs[i] = gorest.NewRequest("GET", "http://example.com/config")
+		}
+		for _, res := range <-client.DoGroupAsync(context.Background(), reqs...) {
+			Expect(res.Error).NotTo(HaveOccurred())
+			body, err := res.Response.Bytes()
+			Expect(err).NotTo(HaveOccurred())
+			Expect(string(body)).To(Equal("warm"))
+		}
+		Expect(atomic.LoadInt32(&hits)).To(Equal(int32(1)))
+	})
+})
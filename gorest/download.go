@@ -0,0 +1,450 @@
+package gorest
+
+import (
+	"context"
+	"crypto/sha256"
+	"encoding/hex"
+	"errors"
+	"fmt"
+	"io"
+	"net/http"
+	"os"
+	"strconv"
+	"strings"
+	"sync"
+	"time"
+)
+
+// DownloadOptions configures Client.Download.
+type DownloadOptions struct {
+	// SHA256 is the expected hex-encoded SHA-256 checksum of the file. Empty skips verification.
+	SHA256 string
+	// MaxAttempts limits consecutive attempts that make no progress before giving up. Defaults to 3.
+	MaxAttempts int
+	// RetryDelay is the wait before resuming an interrupted transfer. Defaults to 1 second.
+	RetryDelay time.Duration
+	// Progress reports the bytes written to the file against the total size (-1 when unknown).
+	Progress ProgressFunc
+	// Concurrency is the number of byte ranges fetched in parallel when the server advertises
+	// "Accept-Ranges: bytes" and a Content-Length in response to a HEAD request.
+	// Values below 2 download the file as a single stream, which is also the fallback otherwise.
+	Concurrency int
+	// MinChunkSize is the smallest range fetched in parallel mode. Defaults to 1 MiB.
+	MinChunkSize int64
+}
+
+// ChecksumError is returned by Client.Download when the downloaded file does not match the expected checksum.
+type ChecksumError struct {
+	Expected string
+	Actual   string
+}
+
+// Error implements the error interface.
+func (e *ChecksumError) Error() string {
+	return fmt.Sprintf("checksum mismatch: expected sha256 %s, got %s", e.Expected, e.Actual)
+}
+
+// Download fetches req into the file at filePath. Data is written to filePath+".part" and renamed to
+// filePath once complete and verified. Interrupted transfers are resumed with Range requests, guarded by
+// If-Range with the ETag or Last-Modified of the first response, including across calls: the validator is
+// kept next to the partial file. If the resource changed, the download restarts from the beginning.
+// With DownloadOptions.Concurrency, ranges are fetched in parallel and written in place instead; such
+// downloads restart from the beginning when interrupted across calls.
+// Non-2xx responses that are not retryable are returned as *HTTPError.
+func (c *Client) Download(ctx context.Context, req *Request, filePath string, opts *DownloadOptions) error {
+	cfg := DownloadOptions{}
+	if opts != nil {
+		cfg = *opts
+	}
+	if cfg.MaxAttempts <= 0 {
+		cfg.MaxAttempts = 3
+	}
+	if cfg.RetryDelay <= 0 {
+		cfg.RetryDelay = time.Second
+	}
+	if cfg.MinChunkSize <= 0 {
+		cfg.MinChunkSize = 1 << 20
+	}
+
+	d := &download{
+		client:   c,
+		req:      req,
+		tmpPath:  filePath + ".part",
+		metaPath: filePath + ".part.meta",
+		progress: cfg.Progress,
+	}
+	d.loadPartial()
+
+	completed := false
+	if cfg.Concurrency > 1 {
+		var err error
+		if completed, err = d.fetchParallel(ctx, &cfg); err != nil {
+			return err
+		}
+	}
+	if !completed {
+		if err := d.fetchSequential(ctx, &cfg); err != nil {
+			return err
+		}
+	}
+
+	if cfg.SHA256 != "" {
+		actual, err := fileSHA256(d.tmpPath)
+		if err != nil {
+			return err
+		}
+		if !strings.EqualFold(actual, cfg.SHA256) {
+			d.discardPartial()
+			return &ChecksumError{Expected: cfg.SHA256, Actual: actual}
+		}
+	}
+	if err := os.Rename(d.tmpPath, filePath); err != nil {
+		return err
+	}
+	_ = os.Remove(d.metaPath)
+	return nil
+}
+
+// fetchSequential downloads the resource as a single stream, resuming after interruptions.
+func (d *download) fetchSequential(ctx context.Context, cfg *DownloadOptions) error {
+	failures := 0
+	for {
+		before := d.offset
+		done, retry, err := d.fetch(ctx)
+		if done {
+			return nil
+		}
+		if !retry || ctx.Err() != nil {
+			return err
+		}
+		if d.offset > before {
+			failures = 0
+		}
+		failures++
+		if failures >= cfg.MaxAttempts {
+			return fmt.Errorf("download failed after %d attempt(s): %w", failures, err)
+		}
+		if err := sleepContext(ctx, cfg.RetryDelay); err != nil {
+			return err
+		}
+	}
+}
+
+// download holds the state of a single-stream, resumable download.
+type download struct {
+	client   *Client
+	req      *Request
+	tmpPath  string
+	metaPath string
+	progress ProgressFunc
+	// offset is the number of bytes already in the partial file.
+	offset int64
+	// validator is the ETag or Last-Modified value used for If-Range.
+	validator string
+}
+
+// loadPartial resumes from an existing partial file if its validator is known.
+func (d *download) loadPartial() {
+	info, err := os.Stat(d.tmpPath)
+	if err != nil {
+		return
+	}
+	validator, err := os.ReadFile(d.metaPath)
+	if err != nil || len(validator) == 0 {
+		return
+	}
+	d.offset = info.Size()
+	d.validator = string(validator)
+}
+
+// discardPartial removes the partial file and its validator.
+func (d *download) discardPartial() {
+	_ = os.Remove(d.tmpPath)
+	_ = os.Remove(d.metaPath)
+	d.offset = 0
+	d.validator = ""
+}
+
+// fetch performs one request, appending to the partial file. It reports whether the download is complete,
+// and otherwise whether the returned error may be resolved by another attempt.
+func (d *download) fetch(ctx context.Context) (done, retry bool, err error) {
+	if d.validator == "" {
+		// Without a validator a partial file cannot be resumed safely.
+		d.offset = 0
+	}
+	attempt := d.req.clone()
+	if d.offset > 0 {
+		attempt.WithHeader("Range", fmt.Sprintf("bytes=%d-", d.offset))
+		attempt.WithHeader("If-Range", d.validator)
+	}
+	resp, err := d.client.roundTrip(ctx, attempt)
+	if err != nil {
+		return false, true, err
+	}
+	defer DrainAndClose(resp)
+
+	flags := os.O_CREATE | os.O_WRONLY
+	switch {
+	case resp.StatusCode == http.StatusPartialContent && d.offset > 0:
+		start, _, _, ok := parseContentRange(resp.Header.Get("Content-Range"))
+		if !ok || start != d.offset {
+			d.discardPartial()
+			return false, true, fmt.Errorf("unexpected Content-Range %q for offset %d", resp.Header.Get("Content-Range"), d.offset)
+		}
+		flags |= os.O_APPEND
+	case resp.StatusCode >= 200 && resp.StatusCode < 300:
+		// The server sent the full resource, because it ignored the range or the resource changed.
+		flags |= os.O_TRUNC
+		d.offset = 0
+		d.validator = responseValidator(resp)
+		if err := os.WriteFile(d.metaPath, []byte(d.validator), 0o644); err != nil {
+			return false, false, err
+		}
+	case resp.StatusCode == http.StatusRequestedRangeNotSatisfiable && d.offset > 0:
+		if _, _, total, ok := parseContentRange(resp.Header.Get("Content-Range")); ok && total == d.offset {
+			return true, false, nil
+		}
+		d.discardPartial()
+		return false, true, newHTTPError(resp.Request, resp)
+	default:
+		return false, DefaultRetryPolicy.ShouldRetry(resp.Request, resp, nil, 1), newHTTPError(resp.Request, resp)
+	}
+
+	f, err := os.OpenFile(d.tmpPath, flags, 0o644)
+	if err != nil {
+		return false, false, err
+	}
+	total := int64(-1)
+	if resp.ContentLength >= 0 {
+		total = d.offset + resp.ContentLength
+	}
+	var body io.Reader = resp.Body
+	if d.progress != nil {
+		body = &progressReader{r: resp.Body, total: total, transferred: d.offset, fn: d.progress}
+	}
+	n, copyErr := io.Copy(f, body)
+	d.offset += n
+	if copyErr == nil {
+		copyErr = f.Sync()
+	}
+	if closeErr := f.Close(); closeErr != nil && copyErr == nil {
+		copyErr = closeErr
+	}
+	if copyErr != nil {
+		return false, true, copyErr
+	}
+	if total >= 0 && d.offset != total {
+		return false, true, io.ErrUnexpectedEOF
+	}
+	return true, false, nil
+}
+
+// responseValidator returns the strong ETag of resp, or its Last-Modified date, for use with If-Range.
+func responseValidator(resp *http.Response) string {
+	if etag := resp.Header.Get("ETag"); etag != "" && !strings.HasPrefix(etag, "W/") {
+		return etag
+	}
+	return resp.Header.Get("Last-Modified")
+}
+
+// parseContentRange parses a Content-Range header of the form "bytes start-end/total" or "bytes */total".
+// Unknown values are returned as -1.
+func parseContentRange(header string) (start, end, total int64, ok bool) {
+	spec, found := strings.CutPrefix(header, "bytes ")
+	if !found {
+		return 0, 0, 0, false
+	}
+	rangePart, totalPart, found := strings.Cut(spec, "/")
+	if !found {
+		return 0, 0, 0, false
+	}
+	total = -1
+	if totalPart != "*" {
+		t, err := strconv.ParseInt(totalPart, 10, 64)
+		if err != nil {
+			return 0, 0, 0, false
+		}
+		total = t
+	}
+	if rangePart == "*" {
+		return -1, -1, total, true
+	}
+	startPart, endPart, found := strings.Cut(rangePart, "-")
+	if !found {
+		return 0, 0, 0, false
+	}
+	start, err1 := strconv.ParseInt(startPart, 10, 64)
+	end, err2 := strconv.ParseInt(endPart, 10, 64)
+	if err := errors.Join(err1, err2); err != nil {
+		return 0, 0, 0, false
+	}
+	return start, end, total, true
+}
+
+// fileSHA256 returns the hex-encoded SHA-256 checksum of the file at path.
+func fileSHA256(path string) (sum string, err error) {
+	f, err := os.Open(path)
+	if err != nil {
+		return "", err
+	}
+	defer func() {
+		if closeErr := f.Close(); closeErr != nil && err == nil {
+			err = closeErr
+		}
+	}()
+	h := sha256.New()
+	if _, err := io.Copy(h, f); err != nil {
+		return "", err
+	}
+	return hex.EncodeToString(h.Sum(nil)), nil
+}
+
+// errRangeIgnored reports that the server answered a range request with the full resource.
+var errRangeIgnored = errors.New("server ignored the range request")
+
+// fetchParallel downloads the resource as concurrent byte ranges written in place. It reports false without
+// an error when the server does not support ranges, so the caller can fall back to a single stream.
+func (d *download) fetchParallel(ctx context.Context, cfg *DownloadOptions) (bool, error) {
+	probe := d.req.clone()
+	probe.method = http.MethodHead
+	resp, err := d.client.roundTrip(ctx, probe)
+	if err != nil {
+		if ctx.Err() != nil {
+			return false, ctx.Err()
+		}
+		return false, nil
+	}
+	DrainAndClose(resp)
+	size := resp.ContentLength
+	if resp.StatusCode != http.StatusOK || resp.Header.Get("Accept-Ranges") != "bytes" || size < 2*cfg.MinChunkSize {
+		return false, nil
+	}
+	validator := responseValidator(resp)
+
+	// Partial files of parallel downloads are sparse and cannot be resumed.
+	d.discardPartial()
+	f, err := os.OpenFile(d.tmpPath, os.O_CREATE|os.O_WRONLY|os.O_TRUNC, 0o644)
+	if err != nil {
+		return false, err
+	}
+	if err := f.Truncate(size); err != nil {
+		_ = f.Close()
+		return false, err
+	}
+
+	chunks := int64(cfg.Concurrency)
+	if maxChunks := size / cfg.MinChunkSize; chunks > maxChunks {
+		chunks = maxChunks
+	}
+	chunkSize := (size + chunks - 1) / chunks
+
+	ctx, cancel := context.WithCancel(ctx)
+	defer cancel()
+	var (
+		wg       sync.WaitGroup
+		mu       sync.Mutex
+		firstErr error
+		received int64
+	)
+	report := func(n int64) {
+		if d.progress == nil {
+			return
+		}
+		mu.Lock()
+		defer mu.Unlock()
+		received += n
+		d.progress(received, size)
+	}
+	for start := int64(0); start < size; start += chunkSize {
+		end := min(start+chunkSize, size) - 1
+		wg.Add(1)
+		go func() {
+			defer wg.Done()
+			if err := d.fetchRange(ctx, cfg, f, start, end, validator, report); err != nil {
+				mu.Lock()
+				if firstErr == nil {
+					firstErr = err
+				}
+				mu.Unlock()
+				cancel()
+			}
+		}()
+	}
+	wg.Wait()
+
+	err = firstErr
+	if err == nil {
+		err = f.Sync()
+	}
+	if closeErr := f.Close(); closeErr != nil && err == nil {
+		err = closeErr
+	}
+	if errors.Is(err, errRangeIgnored) {
+		d.discardPartial()
+		return false, nil
+	}
+	if err != nil {
+		d.discardPartial()
+		return false, err
+	}
+	return true, nil
+}
+
+// fetchRange downloads the bytes start to end (inclusive) into f, resuming within the range after interruptions.
+func (d *download) fetchRange(ctx context.Context, cfg *DownloadOptions, f *os.File, start, end int64, validator string, report func(int64)) error {
+	pos := start
+	failures := 0
+	for {
+		before := pos
+		err := func() error {
+			attempt := d.req.clone()
+			attempt.WithHeader("Range", fmt.Sprintf("bytes=%d-%d", pos, end))
+			if validator != "" {
+				attempt.WithHeader("If-Range", validator)
+			}
+			resp, err := d.client.roundTrip(ctx, attempt)
+			if err != nil {
+				return err
+			}
+			defer DrainAndClose(resp)
+			if resp.StatusCode != http.StatusPartialContent {
+				if resp.StatusCode >= 200 && resp.StatusCode < 300 {
+					return errRangeIgnored
+				}
+				return newHTTPError(resp.Request, resp)
+			}
+			if rangeStart, _, _, ok := parseContentRange(resp.Header.Get("Content-Range")); !ok || rangeStart != pos {
+				return fmt.Errorf("unexpected Content-Range %q for range starting at %d", resp.Header.Get("Content-Range"), pos)
+			}
+			w := io.NewOffsetWriter(f, pos)
+			n, err := io.Copy(w, io.LimitReader(resp.Body, end-pos+1))
+			pos += n
+			report(n)
+			if err != nil {
+				return err
+			}
+			if pos <= end {
+				return io.ErrUnexpectedEOF
+			}
+			return nil
+		}()
+		if err == nil {
+			return nil
+		}
+		var httpErr *HTTPError
+		if ctx.Err() != nil || errors.Is(err, errRangeIgnored) ||
+			(errors.As(err, &httpErr) && httpErr.StatusCode != http.StatusTooManyRequests && httpErr.StatusCode < 500) {
+			return err
+		}
+		if pos > before {
+			failures = 0
+		}
+		failures++
+		if failures >= cfg.MaxAttempts {
+			return fmt.Errorf("range %d-%d failed after %d attempt(s): %w", start, end, failures, err)
+		}
+		if err := sleepContext(ctx, cfg.RetryDelay); err != nil {
+			return err
+		}
+	}
+}
@@ -0,0 +1,184 @@
+package gorest_test
+
+import (
+	"bytes"
+	"context"
+	"crypto/sha256"
+	"encoding/hex"
+	"errors"
+	"net/http"
+	"net/http/httptest"
+	"os"
+	"path/filepath"
+	"strconv"
+	"strings"
+	"sync"
+	"time"
+
+	. "github.com/onsi/ginkgo/v2"
+	. "github.com/onsi/gomega"
+
+	"gorest/gorest"
+)
+
+var _ = Describe("Client.Download", func() {
+	var (
+		content  []byte
+		etag     string
+		mu       sync.Mutex
+		ranges   []string
+		failNext bool
+		server   *httptest.Server
+		dir      string
+		target   string
+	)
+
+	BeforeEach(func() {
+		content = []byte(strings.Repeat("0123456789", 10000))
+		etag = `"v1"`
+		ranges = nil
+		failNext = false
+		server = httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+			mu.Lock()
+			ranges = append(ranges, r.Header.Get("Range"))
+			fail := failNext
+			failNext = false
+			mu.Unlock()
+			if r.URL.Path == "/missing" {
+				w.WriteHeader(http.StatusNotFound)
+				return
+			}
+			w.Header().Set("ETag", etag)
+			if fail {
+				// Send half of the body, then drop the connection.
+				w.Header().Set("Content-Length", strconv.Itoa(len(content)))
+				_, _ = w.Write(content[:len(content)/2])
+				w.(http.Flusher).Flush()
+				panic(http.ErrAbortHandler)
+			}
+			http.ServeContent(w, r, "data.bin", time.Time{}, bytes.NewReader(content))
+		}))
+		dir = GinkgoT().TempDir()
+		target = filepath.Join(dir, "data.bin")
+	})
+
+	AfterEach(func() {
+		server.Close()
+	})
+
+	It("should resume an interrupted transfer with a Range request", func() {
+		failNext = true
+		var lastReceived, lastTotal int64
+		err := gorest.NewClient().Download(context.Background(), gorest.NewRequest("GET", server.URL), target, &gorest.DownloadOptions{
+			RetryDelay: time.Millisecond,
+			Progress:   func(received, total int64) { lastReceived, lastTotal = received, total },
+		})
+		Expect(err).NotTo(HaveOccurred())
+		data, err := os.ReadFile(target)
+		Expect(err).NotTo(HaveOccurred())
+		Expect(data).To(Equal(content))
+		Expect(ranges).To(HaveLen(2))
+		Expect(ranges[0]).To(BeEmpty())
+		Expect(ranges[1]).To(MatchRegexp(`^bytes=\d+-$`))
+		Expect(lastReceived).To(Equal(int64(len(content))))
+		Expect(lastTotal).To(Equal(int64(len(content))))
+		_, err = os.Stat(target + ".part")
+		Expect(os.IsNotExist(err)).To(BeTrue())
+	})
+
+	It("should resume a partial file left by a previous call", func() {
+		Expect(os.WriteFile(target+".part", content[:1000], 0o644)).To(Succeed())
+		Expect(os.WriteFile(target+".part.meta", []byte(etag), 0o644)).To(Succeed())
+		err := gorest.NewClient().Download(context.Background(), gorest.NewRequest("GET", server.URL), target, nil)
+		Expect(err).NotTo(HaveOccurred())
+		Expect(ranges).To(Equal([]string{"bytes=1000-"}))
+		data, err := os.ReadFile(target)
+		Expect(err).NotTo(HaveOccurred())
+		Expect(data).To(Equal(content))
+	})
+
+	It("should restart from scratch when the resource changed", func() {
+		Expect(os.WriteFile(target+".part", []byte("stale data"), 0o644)).To(Succeed())
+		Expect(os.WriteFile(target+".part.meta", []byte(`"v0"`), 0o644)).To(Succeed())
+		err := gorest.NewClient().Download(context.Background(), gorest.NewRequest("GET", server.URL), target, nil)
+		Expect(err).NotTo(HaveOccurred())
+		data, err := os.ReadFile(target)
+		Expect(err).NotTo(HaveOccurred())
+		Expect(data).To(Equal(content))
+	})
+
+	It("should verify the SHA-256 checksum", func() {
+		sum := sha256.Sum256(content)
+		err := gorest.NewClient().Download(context.Background(), gorest.NewRequest("GET", server.URL), target, &gorest.DownloadOptions{
+			SHA256: hex.EncodeToString(sum[:]),
+		})
+		Expect(err).NotTo(HaveOccurred())
+
+		other := filepath.Join(dir, "other.bin")
+		err = gorest.NewClient().Download(context.Background(), gorest.NewRequest("GET", server.URL), other, &gorest.DownloadOptions{
+			SHA256: strings.Repeat("0", 64),
+		})
+		var checksumErr *gorest.ChecksumError
+		Expect(errors.As(err, &checksumErr)).To(BeTrue())
+		Expect(checksumErr.Actual).To(Equal(hex.EncodeToString(sum[:])))
+		_, err = os.Stat(other)
+		Expect(os.IsNotExist(err)).To(BeTrue())
+		_, err = os.Stat(other + ".part")
+		Expect(os.IsNotExist(err)).To(BeTrue())
+	})
+
+	Context("with concurrency", func() {
+		It("should fetch byte ranges in parallel", func() {
+			var lastReceived int64
+			err := gorest.NewClient().Download(context.Background(), gorest.NewRequest("GET", server.URL), target, &gorest.DownloadOptions{
+				Concurrency:  4,
+				MinChunkSize: 10000,
+				Progress:     func(received, _ int64) { lastReceived = received },
+			})
+			Expect(err).NotTo(HaveOccurred())
+			data, err := os.ReadFile(target)
+			Expect(err).NotTo(HaveOccurred())
+			Expect(data).To(Equal(content))
+			Expect(lastReceived).To(Equal(int64(len(content))))
+			Expect(ranges).To(ConsistOf("", "bytes=0-24999", "bytes=25000-49999", "bytes=50000-74999", "bytes=75000-99999"))
+		})
+
+		It("should limit the number of ranges by the minimum chunk size", func() {
+			err := gorest.NewClient().Download(context.Background(), gorest.NewRequest("GET", server.URL), target, &gorest.DownloadOptions{
+				Concurrency:  8,
+				MinChunkSize: 40000,
+			})
+			Expect(err).NotTo(HaveOccurred())
+			Expect(ranges).To(ConsistOf("", "bytes=0-49999", "bytes=50000-99999"))
+		})
+
+		It("should fall back to a single stream when ranges are not supported", func() {
+			plain := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+				mu.Lock()
+				ranges = append(ranges, r.Method+" "+r.Header.Get("Range"))
+				mu.Unlock()
+				w.Header().Set("Content-Length", strconv.Itoa(len(content)))
+				if r.Method != http.MethodHead {
+					_, _ = w.Write(content)
+				}
+			}))
+			defer plain.Close()
+
+			err := gorest.NewClient().Download(context.Background(), gorest.NewRequest("GET", plain.URL), target, &gorest.DownloadOptions{
+				Concurrency:  4,
+				MinChunkSize: 10000,
+			})
+			Expect(err).NotTo(HaveOccurred())
+			Expect(ranges).To(Equal([]string{"HEAD ", "GET "}))
+			data, err := os.ReadFile(target)
+			Expect(err).NotTo(HaveOccurred())
+			Expect(data).To(Equal(content))
+		})
+	})
+
+	It("should return an HTTPError for non-retryable statuses", func() {
+		err := gorest.NewClient().Download(context.Background(), gorest.NewRequest("GET", server.URL+"/missing"), target, nil)
+		Expect(gorest.IsNotFound(err)).To(BeTrue())
+		Expect(ranges).To(HaveLen(1))
+	})
+})
@@ -0,0 +1,152 @@
+package gorest
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"io"
+	"net/http"
+	"sync/atomic"
+)
+
+// maxErrorBodySize limits how much of a non-2xx response body is kept in an HTTPError.
+const maxErrorBodySize = 4096
+
+// HTTPError describes a non-2xx HTTP response. It is returned by Client.Do and Client.DoStream
+// when the client is configured with WithStatusErrors(true), and can be matched with errors.As.
+type HTTPError struct {
+	// StatusCode is the HTTP status code of the response.
+	StatusCode int
+	// Status is the HTTP status line of the response (e.g. "404 Not Found").
+	Status string
+	// Header holds the response headers.
+	Header http.Header
+	// Body holds the beginning of the response body, truncated to a few kilobytes.
+	Body []byte
+	// Truncated reports whether Body was cut short.
+	Truncated bool
+	// Method is the method of the request that produced the response.
+	Method string
+	// URL is the URL of the request that produced the response.
+	URL string
+	// Attempts is the number of attempts made for the request (greater than one when retried).
+	Attempts int
+}
+
+// Error implements the error interface.
+func (e *HTTPError) Error() string {
+	msg := fmt.Sprintf("%s %s: unexpected status %d", e.Method, e.URL, e.StatusCode)
+	if e.Attempts > 1 {
+		msg += fmt.Sprintf(" after %d attempts", e.Attempts)
+	}
+	if len(e.Body) > 0 {
+		msg += ": " + string(e.Body)
+		if e.Truncated {
+			msg += "..."
+		}
+	}
+	return msg
+}
+
+// newHTTPError builds an HTTPError from resp, reading at most maxErrorBodySize bytes of its body.
+// The response body is drained and closed.
+func newHTTPError(req *http.Request, resp *http.Response) *HTTPError {
+	httpErr := &HTTPError{
+		StatusCode: resp.StatusCode,
+		Status:     resp.Status,
+		Header:     resp.Header,
+		Method:     req.Method,
+		URL:        req.URL.String(),
+		Attempts:   attemptsFromContext(req.Context()),
+	}
+	if resp.Body != nil {
+		body, _ := io.ReadAll(io.LimitReader(resp.Body, maxErrorBodySize+1))
+		if len(body) > maxErrorBodySize {
+			body = body[:maxErrorBodySize]
+			httpErr.Truncated = true
+		}
+		httpErr.Body = body
+		DrainAndClose(resp)
+	}
+	return httpErr
+}
+
+// IsStatus reports whether err is, or wraps, an HTTPError with the given status code.
+func IsStatus(err error, statusCode int) bool {
+	var httpErr *HTTPError
+	return errors.As(err, &httpErr) && httpErr.StatusCode == statusCode
+}
+
+// IsBadRequest reports whether err is, or wraps, an HTTPError with status 400.
+func IsBadRequest(err error) bool {
+	return IsStatus(err, http.StatusBadRequest)
+}
+
+// IsUnauthorized reports whether err is, or wraps, an HTTPError with status 401.
+func IsUnauthorized(err error) bool {
+	return IsStatus(err, http.StatusUnauthorized)
+}
+
+// IsForbidden reports whether err is, or wraps, an HTTPError with status 403.
+func IsForbidden(err error) bool {
+	return IsStatus(err, http.StatusForbidden)
+}
+
+// IsNotFound reports whether err is, or wraps, an HTTPError with status 404.
+func IsNotFound(err error) bool {
+	return IsStatus(err, http.StatusNotFound)
+}
+
+// IsConflict reports whether err is, or wraps, an HTTPError with status 409.
+func IsConflict(err error) bool {
+	return IsStatus(err, http.StatusConflict)
+}
+
+// IsRateLimited reports whether err is, or wraps, an HTTPError with status 429.
+func IsRateLimited(err error) bool {
+	return IsStatus(err, http.StatusTooManyRequests)
+}
+
+// IsClientError reports whether err is, or wraps, an HTTPError with a 4xx status.
+func IsClientError(err error) bool {
+	var httpErr *HTTPError
+	return errors.As(err, &httpErr) && httpErr.StatusCode >= 400 && httpErr.StatusCode < 500
+}
+
+// IsServerError reports whether err is, or wraps, an HTTPError with a 5xx status.
+func IsServerError(err error) bool {
+	var httpErr *HTTPError
+	return errors.As(err, &httpErr) && httpErr.StatusCode >= 500
+}
+
+// attemptCounterKey is the context key under which the attempt counter of a request is stored.
+type attemptCounterKey struct{}
+
+// attemptCounter records how many attempts were made for a single request.
+type attemptCounter struct {
+	n atomic.Int32
+}
+
+// withAttemptCounter returns a context carrying a new attempt counter. A counter inherited from ctx is replaced,
+// so requests sent while handling another one, such as token requests, do not count towards its attempts.
+func withAttemptCounter(ctx context.Context) (context.Context, *attemptCounter) {
+	counter := &attemptCounter{}
+	return context.WithValue(ctx, attemptCounterKey{}, counter), counter
+}
+
+// recordAttempt stores the attempt number in the request's attempt counter, if any.
+func recordAttempt(ctx context.Context, attempt int) {
+	if counter, ok := ctx.Value(attemptCounterKey{}).(*attemptCounter); ok {
+		counter.n.Store(int32(attempt))
+	}
+}
+
+// attemptsFromContext returns the number of attempts recorded in ctx, defaulting to one.
+func attemptsFromContext(ctx context.Context) int {
+	if counter, ok := ctx.Value(attemptCounterKey{}).(*attemptCounter); ok {
+		if n := counter.n.Load(); n > 0 {
+			return int(n)
+		}
+	}
+	return 1
+}
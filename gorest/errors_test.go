@@ -0,0 +1,148 @@
+package gorest_test
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"sync/atomic"
+	"time"
+
+	. "github.com/onsi/ginkgo/v2"
+	. "github.com/onsi/gomega"
+
+	"gorest/gorest"
+)
+
+var _ = Describe("HTTPError", func() {
+	var testServer *httptest.Server
+
+	BeforeEach(func() {
+		testServer = httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+			switch r.URL.Path {
+			case "/ok":
+				_, _ = fmt.Fprint(w, "ok")
+			case "/missing":
+				w.Header().Set("X-Reason", "gone")
+				w.WriteHeader(http.StatusNotFound)
+				_, _ = fmt.Fprint(w, "no such thing")
+			case "/limited":
+				w.WriteHeader(http.StatusTooManyRequests)
+			case "/large":
+				w.WriteHeader(http.StatusBadRequest)
+				_, _ = fmt.Fprint(w, strings.Repeat("x", 10000))
+			default:
+				w.WriteHeader(http.StatusInternalServerError)
+			}
+		}))
+	})
+
+	AfterEach(func() {
+		testServer.Close()
+	})
+
+	It("should not return errors for non-2xx responses by default", func() {
+		client := gorest.NewClient()
+		resp, err := client.Do(context.Background(), gorest.NewRequest("GET", testServer.URL+"/missing"))
+		Expect(err).NotTo(HaveOccurred())
+		Expect(resp.StatusCode).To(Equal(http.StatusNotFound))
+	})
+
+	It("should return a typed HTTPError when status errors are enabled", func() {
+		client := gorest.NewClient(gorest.WithStatusErrors(true))
+		resp, err := client.Do(context.Background(), gorest.NewRequest("GET", testServer.URL+"/missing"))
+		Expect(resp).To(BeNil())
+		var httpErr *gorest.HTTPError
+		Expect(errors.As(err, &httpErr)).To(BeTrue())
+		Expect(httpErr.StatusCode).To(Equal(http.StatusNotFound))
+		Expect(httpErr.Header.Get("X-Reason")).To(Equal("gone"))
+		Expect(string(httpErr.Body)).To(Equal("no such thing"))
+		Expect(httpErr.Method).To(Equal("GET"))
+		Expect(httpErr.URL).To(Equal(testServer.URL + "/missing"))
+		Expect(httpErr.Attempts).To(Equal(1))
+		Expect(gorest.IsNotFound(err)).To(BeTrue())
+		Expect(gorest.IsClientError(err)).To(BeTrue())
+		Expect(gorest.IsServerError(err)).To(BeFalse())
+	})
+
+	It("should succeed for 2xx responses when status errors are enabled", func() {
+		client := gorest.NewClient(gorest.WithStatusErrors(true))
+		resp, err := client.Do(context.Background(), gorest.NewRequest("GET", testServer.URL+"/ok"))
+		Expect(err).NotTo(HaveOccurred())
+		body, err := resp.Bytes()
+		Expect(err).NotTo(HaveOccurred())
+		Expect(string(body)).To(Equal("ok"))
+	})
+
+	It("should match rate limiting and server errors from DoStream", func() {
+		client := gorest.NewClient(gorest.WithStatusErrors(true))
+		_, err := client.DoStream(context.Background(), gorest.NewRequest("GET", testServer.URL+"/limited"))
+		Expect(gorest.IsRateLimited(err)).To(BeTrue())
+		_, err = client.DoStream(context.Background(), gorest.NewRequest("GET", testServer.URL+"/boom"))
+		Expect(gorest.IsServerError(err)).To(BeTrue())
+		Expect(gorest.IsStatus(err, http.StatusInternalServerError)).To(BeTrue())
+	})
+
+	It("should truncate large error bodies", func() {
+		client := gorest.NewClient(gorest.WithStatusErrors(true))
+		_, err := client.Do(context.Background(), gorest.NewRequest("GET", testServer.URL+"/large"))
+		var httpErr *gorest.HTTPError
+		Expect(errors.As(err, &httpErr)).To(BeTrue())
+		Expect(httpErr.Truncated).To(BeTrue())
+		Expect(len(httpErr.Body)).To(BeNumerically("<", 10000))
+		Expect(gorest.IsBadRequest(err)).To(BeTrue())
+	})
+
+	It("should report the number of attempts made by RetryMiddleware", func() {
+		var calls int32
+		rt := gorest.RoundTripFunc(func(req *http.Request) (*http.Response, error) {
+			if atomic.AddInt32(&calls, 1) == 1 {
+				return nil, errors.New("temporary error")
+			}
+			return &http.Response{
+				StatusCode: http.StatusConflict,
+				Header:     http.Header{},
+				Body:       http.NoBody,
+			}, nil
+		})
+		client := gorest.NewClient(
+			gorest.WithTransport(rt),
+			gorest.WithMiddlewares(gorest.RetryMiddleware(3, time.Millisecond)),
+			gorest.WithStatusErrors(true),
+		)
+		_, err := client.Do(context.Background(), gorest.NewRequest("GET", "http://dummy"))
+		var httpErr *gorest.HTTPError
+		Expect(errors.As(err, &httpErr)).To(BeTrue())
+		Expect(httpErr.Attempts).To(Equal(2))
+		Expect(gorest.IsConflict(err)).To(BeTrue())
+	})
+
+	It("should not count the attempts of requests sent while handling the request", func() {
+		var calls int32
+		inner := gorest.NewClient(
+			gorest.WithTransport(gorest.RoundTripFunc(func(req *http.Request) (*http.Response, error) {
+				if atomic.AddInt32(&calls, 1) < 3 {
+					return nil, errors.New("temporary error")
+				}
+				return &http.Response{StatusCode: http.StatusOK, Header: http.Header{}, Body: http.NoBody}, nil
+			})),
+			gorest.WithMiddlewares(gorest.RetryMiddleware(3, time.Millisecond)),
+		)
+		nested := func(next gorest.RoundTripFunc) gorest.RoundTripFunc {
+			return func(req *http.Request) (*http.Response, error) {
+				if _, err := inner.Do(req.Context(), gorest.NewRequest("GET", "http://dummy/token")); err != nil {
+					return nil, err
+				}
+				return next(req)
+			}
+		}
+		client := gorest.NewClient(gorest.WithMiddlewares(nested), gorest.WithStatusErrors(true))
+		_, err := client.Do(context.Background(), gorest.NewRequest("GET", testServer.URL+"/missing"))
+		var httpErr *gorest.HTTPError
+		Expect(errors.As(err, &httpErr)).To(BeTrue())
+		Expect(atomic.LoadInt32(&calls)).To(Equal(int32(3)))
+		Expect(httpErr.Attempts).To(Equal(1))
+	})
+})
@@ -0,0 +1,207 @@
+package gorest
+
+import (
+	"context"
+	"net/http"
+	"slices"
+	"sync"
+	"time"
+)
+
+// HedgeConfig configures the HedgeMiddleware.
+type HedgeConfig struct {
+	// Delay is how long to wait for a response before sending a hedged request. Defaults to 100ms.
+	// When Percentile is set, Delay is used until MinSamples latencies have been observed.
+	Delay time.Duration
+	// Percentile, if set (e.g. 0.95), derives the delay from the latencies of recent successful requests.
+	Percentile float64
+	// MinSamples is the number of latencies needed before Percentile is used. Defaults to 20.
+	MinSamples int
+	// Window is the number of recent latencies kept for Percentile. Defaults to 200.
+	Window int
+	// MaxHedges is the number of hedged requests sent in addition to the original one. Defaults to 1.
+	MaxHedges int
+	// ShouldHedge decides whether a request may be hedged. Defaults to IsIdempotent.
+	ShouldHedge func(req *http.Request) bool
+	// IsSuccess decides whether an attempt won. Defaults to responses with a status below 500.
+	// If no attempt succeeds, the outcome of the last attempt to finish is returned.
+	IsSuccess func(resp *http.Response, err error) bool
+}
+
+// HedgeMiddleware returns a middleware that reduces tail latency by sending a duplicate of a request
+// that has not responded within the hedging delay. The first successful response is returned;
+// the other attempts are cancelled and their responses drained and closed.
+// Note: The request body is fully buffered in memory so that it can be sent more than once.
+func HedgeMiddleware(config *HedgeConfig) Middleware {
+	cfg := HedgeConfig{}
+	if config != nil {
+		cfg = *config
+	}
+	if cfg.Delay <= 0 {
+		cfg.Delay = 100 * time.Millisecond
+	}
+	if cfg.MinSamples <= 0 {
+		cfg.MinSamples = 20
+	}
+	if cfg.Window <= 0 {
+		cfg.Window = 200
+	}
+	if cfg.MaxHedges <= 0 {
+		cfg.MaxHedges = 1
+	}
+	if cfg.ShouldHedge == nil {
+		cfg.ShouldHedge = IsIdempotent
+	}
+	if cfg.IsSuccess == nil {
+		cfg.IsSuccess = func(resp *http.Response, err error) bool {
+			return err == nil && resp.StatusCode < 500
+		}
+	}
+	latencies := &latencyWindow{size: cfg.Window}
+	delay := func() time.Duration {
+		if cfg.Percentile > 0 {
+			if d, ok := latencies.percentile(cfg.Percentile, cfg.MinSamples); ok {
+				return d
+			}
+		}
+		return cfg.Delay
+	}
+
+	return func(next RoundTripFunc) RoundTripFunc {
+		return func(req *http.Request) (*http.Response, error) {
+			if !cfg.ShouldHedge(req) {
+				return next(req)
+			}
+			bodyBytes, err := bufferRequestBody(req)
+			if err != nil {
+				return nil, err
+			}
+
+			type result struct {
+				index   int
+				resp    *http.Response
+				err     error
+				latency time.Duration
+			}
+			total := cfg.MaxHedges + 1
+			results := make(chan result, total)
+			cancels := make([]context.CancelFunc, 0, total)
+			launch := func() {
+				ctx, cancel := context.WithCancel(req.Context())
+				index := len(cancels)
+				cancels = append(cancels, cancel)
+				attempt := cloneWithBody(req.WithContext(ctx), bodyBytes)
+				go func() {
+					start := time.Now()
+					resp, err := next(attempt)
+					results <- result{index: index, resp: resp, err: err, latency: time.Since(start)}
+				}()
+			}
+			// abandon cancels every attempt except keep and drains the responses of the pending ones.
+			abandon := func(keep, pending int) {
+				for i, cancel := range cancels {
+					if i != keep {
+						cancel()
+					}
+				}
+				if pending == 0 {
+					return
+				}
+				go func() {
+					for range pending {
+						if r := <-results; r.resp != nil {
+							DrainAndClose(r.resp)
+						}
+					}
+				}()
+			}
+
+			launch()
+			pending := 1
+			timer := time.NewTimer(delay())
+			defer timer.Stop()
+			// finish returns the outcome of r, cancelling its context once the response body is closed.
+			finish := func(r result) (*http.Response, error) {
+				if r.resp != nil && r.resp.Body != nil {
+					r.resp.Body = &releaseOnClose{ReadCloser: r.resp.Body, release: cancels[r.index]}
+				} else {
+					cancels[r.index]()
+				}
+				return r.resp, r.err
+			}
+			var last *result
+			discardLast := func() {
+				if last != nil && last.resp != nil {
+					DrainAndClose(last.resp)
+				}
+			}
+			for {
+				select {
+				case <-timer.C:
+					if len(cancels) < total {
+						launch()
+						pending++
+						timer.Reset(delay())
+					}
+				case r := <-results:
+					pending--
+					if cfg.IsSuccess(r.resp, r.err) {
+						latencies.add(r.latency)
+						discardLast()
+						abandon(r.index, pending)
+						return finish(r)
+					}
+					discardLast()
+					last = &r
+					if pending > 0 {
+						continue
+					}
+					if len(cancels) == total {
+						abandon(r.index, 0)
+						return finish(r)
+					}
+					// Every attempt so far failed, so hedge right away.
+					launch()
+					pending++
+					timer.Reset(delay())
+				case <-req.Context().Done():
+					discardLast()
+					abandon(-1, pending)
+					return nil, req.Context().Err()
+				}
+			}
+		}
+	}
+}
+
+// latencyWindow keeps the most recent latencies to compute percentiles.
+type latencyWindow struct {
+	mu      sync.Mutex
+	size    int
+	samples []time.Duration
+	next    int
+}
+
+func (w *latencyWindow) add(d time.Duration) {
+	w.mu.Lock()
+	defer w.mu.Unlock()
+	if len(w.samples) < w.size {
+		w.samples = append(w.samples, d)
+		return
+	}
+	w.samples[w.next] = d
+	w.next = (w.next + 1) % w.size
+}
+
+// percentile returns the p-th percentile (0 < p <= 1) of the samples, if there are at least minSamples.
+func (w *latencyWindow) percentile(p float64, minSamples int) (time.Duration, bool) {
+	w.mu.Lock()
+	sorted := slices.Clone(w.samples)
+	w.mu.Unlock()
+	if len(sorted) < minSamples || len(sorted) == 0 {
+		return 0, false
+	}
+	slices.Sort(sorted)
+	i := int(p*float64(len(sorted))+0.5) - 1
+	return sorted[min(max(i, 0), len(sorted)-1)], true
+}
@@ -0,0 +1,133 @@
+package gorest_test
+
+import (
+	"io"
+	"net/http"
+	"strings"
+	"sync"
+	"sync/atomic"
+	"time"
+
+	. "github.com/onsi/ginkgo/v2"
+	. "github.com/onsi/gomega"
+
+	"gorest/gorest"
+)
+
+var _ = Describe("HedgeMiddleware", func() {
+	var (
+		mu        sync.Mutex
+		calls     int
+		bodies    []string
+		cancelled int32
+		closed    int32
+		// slowCalls is the set of call numbers (starting at 1) that respond after slowDelay.
+		slowCalls map[int]bool
+		slowDelay time.Duration
+		next      gorest.RoundTripFunc
+	)
+
+	BeforeEach(func() {
+		calls = 0
+		bodies = nil
+		atomic.StoreInt32(&cancelled, 0)
+		atomic.StoreInt32(&closed, 0)
+		slowCalls = map[int]bool{}
+		slowDelay = time.Second
+		next = func(req *http.Request) (*http.Response, error) {
+			var body []byte
+			if req.Body != nil {
+				body, _ = io.ReadAll(req.Body)
+			}
+			mu.Lock()
+			calls++
+			n := calls
+			bodies = append(bodies, string(body))
+			mu.Unlock()
+			if slowCalls[n] {
+				select {
+				case <-time.After(slowDelay):
+				case <-req.Context().Done():
+					atomic.AddInt32(&cancelled, 1)
+					return nil, req.Context().Err()
+				}
+			}
+			return &http.Response{
+				StatusCode: 200,
+				Header:     http.Header{"X-Call": {string(rune('0' + n))}},
+				Body: &dummyReadCloser{
+					Reader:    strings.NewReader("ok"),
+					closeFunc: func() error { atomic.AddInt32(&closed, 1); return nil },
+				},
+			}, nil
+		}
+	})
+
+	send := func(rt gorest.RoundTripFunc, method string, body string) (*http.Response, time.Duration) {
+		req, err := http.NewRequest(method, "http://example.com", strings.NewReader(body))
+		Expect(err).NotTo(HaveOccurred())
+		start := time.Now()
+		resp, err := rt(req)
+		Expect(err).NotTo(HaveOccurred())
+		return resp, time.Since(start)
+	}
+
+	It("should not hedge a request that responds within the delay", func() {
+		rt := gorest.HedgeMiddleware(&gorest.HedgeConfig{Delay: 50 * time.Millisecond})(next)
+		resp, _ := send(rt, "GET", "")
+		Expect(resp.Header.Get("X-Call")).To(Equal("1"))
+		Expect(resp.Body.Close()).To(Succeed())
+		Expect(calls).To(Equal(1))
+	})
+
+	It("should return the hedged response and cancel the slow attempt", func() {
+		slowCalls[1] = true
+		rt := gorest.HedgeMiddleware(&gorest.HedgeConfig{Delay: 20 * time.Millisecond})(next)
+		resp, elapsed := send(rt, "PUT", "payload")
+		Expect(resp.Header.Get("X-Call")).To(Equal("2"))
+		Expect(elapsed).To(BeNumerically("<", 500*time.Millisecond))
+		Expect(resp.Body.Close()).To(Succeed())
+		Eventually(func() int32 { return atomic.LoadInt32(&cancelled) }).Should(Equal(int32(1)))
+		mu.Lock()
+		defer mu.Unlock()
+		Expect(bodies).To(Equal([]string{"payload", "payload"}))
+	})
+
+	It("should drain and close the losing responses", func() {
+		slowCalls[1] = true
+		slowCalls[2] = true
+		slowDelay = 60 * time.Millisecond
+		rt := gorest.HedgeMiddleware(&gorest.HedgeConfig{Delay: 10 * time.Millisecond, MaxHedges: 2})(next)
+		resp, _ := send(rt, "GET", "")
+		Expect(resp.Header.Get("X-Call")).To(Equal("3"))
+		Expect(resp.Body.Close()).To(Succeed())
+		Eventually(func() int32 {
+			return atomic.LoadInt32(&closed) + atomic.LoadInt32(&cancelled)
+		}).Should(Equal(int32(3)))
+	})
+
+	It("should not hedge non-idempotent requests", func() {
+		slowCalls[1] = true
+		slowDelay = 50 * time.Millisecond
+		rt := gorest.HedgeMiddleware(&gorest.HedgeConfig{Delay: 10 * time.Millisecond})(next)
+		resp, _ := send(rt, "POST", "payload")
+		Expect(resp.Header.Get("X-Call")).To(Equal("1"))
+		Expect(calls).To(Equal(1))
+	})
+
+	It("should derive the delay from the latency percentile", func() {
+		rt := gorest.HedgeMiddleware(&gorest.HedgeConfig{
+			Delay:      time.Hour,
+			Percentile: 0.9,
+			MinSamples: 5,
+		})(next)
+		for i := 0; i < 5; i++ {
+			resp, _ := send(rt, "GET", "")
+			Expect(resp.Body.Close()).To(Succeed())
+		}
+		slowCalls[6] = true
+		resp, elapsed := send(rt, "GET", "")
+		Expect(resp.Header.Get("X-Call")).To(Equal("7"))
+		Expect(elapsed).To(BeNumerically("<", 500*time.Millisecond))
+	})
+})
@@ -0,0 +1,202 @@
+package gorest
+
+import (
+	"bytes"
+	"crypto/hmac"
+	"crypto/sha256"
+	"encoding/base64"
+	"encoding/hex"
+	"errors"
+	"fmt"
+	"hash"
+	"io"
+	"net/http"
+	"regexp"
+	"strconv"
+	"time"
+)
+
+// DefaultHMACTemplate is the canonical string signed by default.
+const DefaultHMACTemplate = "{method}\n{path}\n{timestamp}\n{body}"
+
+// HMACConfig configures the HMACSignerMiddleware and HMACVerifierMiddleware. It is required, with a Key.
+type HMACConfig struct {
+	// Key is the shared secret. It must not be empty.
+	Key []byte
+	// Hash is the hash function. Defaults to sha256.New.
+	Hash func() hash.Hash
+	// SignatureHeader holds the signature. Defaults to "X-Signature".
+	SignatureHeader string
+	// TimestampHeader holds the signing time in Unix seconds. Defaults to "X-Timestamp".
+	TimestampHeader string
+	// Template describes the signed string. It may contain the placeholders {method}, {path}, {query},
+	// {timestamp}, {body}, {body_sha256} and {header:Name}. Defaults to DefaultHMACTemplate.
+	Template string
+	// Prefix is prepended to the encoded signature, as in "sha256=".
+	Prefix string
+	// Base64 encodes the signature in base64 instead of hex.
+	Base64 bool
+	// MaxSkew is how far the timestamp of a verified response may be from the current time. Defaults to 5 minutes.
+	MaxSkew time.Duration
+	// Now returns the current time. Defaults to time.Now.
+	Now func() time.Time
+}
+
+// ErrInvalidSignature is matched (with errors.Is) by the errors returned when a response fails HMAC verification.
+var ErrInvalidSignature = errors.New("invalid signature")
+
+// SignatureError is returned by the HMACVerifierMiddleware when a response signature is missing or wrong.
+type SignatureError struct {
+	// Reason describes why verification failed.
+	Reason string
+	// StatusCode is the status code of the rejected response.
+	StatusCode int
+}
+
+// Error implements the error interface.
+func (e *SignatureError) Error() string {
+	return fmt.Sprintf("invalid response signature (status %d): %s", e.StatusCode, e.Reason)
+}
+
+// Is reports whether target is ErrInvalidSignature.
+func (e *SignatureError) Is(target error) bool {
+	return target == ErrInvalidSignature
+}
+
+// HMACSignerMiddleware returns a middleware that signs requests with an HMAC over the canonical string
+// described by the config's Template, setting the timestamp and signature headers.
+// Place it after RetryMiddleware in WithMiddlewares so that each attempt gets a fresh timestamp.
+// If the config is nil or has no key, every request fails with an error.
+// Note: The request body is fully buffered in memory to compute the signature.
+func HMACSignerMiddleware(config *HMACConfig) Middleware {
+	cfg, configErr := config.withDefaults()
+	return func(next RoundTripFunc) RoundTripFunc {
+		return func(req *http.Request) (*http.Response, error) {
+			if configErr != nil {
+				return nil, configErr
+			}
+			body, err := bufferRequestBody(req)
+			if err != nil {
+				return nil, err
+			}
+			signed := cloneWithBody(req, body)
+			timestamp := strconv.FormatInt(cfg.Now().Unix(), 10)
+			signed.Header.Set(cfg.TimestampHeader, timestamp)
+			signed.Header.Set(cfg.SignatureHeader, cfg.sign(req, signed.Header, timestamp, body))
+			return next(signed)
+		}
+	}
+}
+
+// HMACVerifierMiddleware returns a middleware that verifies the HMAC signature of responses,
+// computed like HMACSignerMiddleware over the canonical string of the request method and path
+// and the response timestamp, headers and body. Responses with a missing or wrong signature, or a
+// timestamp more than MaxSkew away, are closed and a *SignatureError is returned.
+// If the config is nil or has no key, every request fails with an error before it is sent.
+// Note: The response body is fully buffered in memory to verify the signature.
+func HMACVerifierMiddleware(config *HMACConfig) Middleware {
+	cfg, configErr := config.withDefaults()
+	return func(next RoundTripFunc) RoundTripFunc {
+		return func(req *http.Request) (*http.Response, error) {
+			if configErr != nil {
+				return nil, configErr
+			}
+			resp, err := next(req)
+			if err != nil {
+				return nil, err
+			}
+			body, err := io.ReadAll(resp.Body)
+			_ = resp.Body.Close()
+			if err != nil {
+				return nil, err
+			}
+			if reason := cfg.verify(req, resp.Header, body); reason != "" {
+				return nil, &SignatureError{Reason: reason, StatusCode: resp.StatusCode}
+			}
+			resp.Body = io.NopCloser(bytes.NewReader(body))
+			return resp, nil
+		}
+	}
+}
+
+// withDefaults validates the config and returns a copy with defaults applied.
+func (c *HMACConfig) withDefaults() (HMACConfig, error) {
+	if c == nil {
+		return HMACConfig{}, errors.New("hmac: config is required")
+	}
+	if len(c.Key) == 0 {
+		return HMACConfig{}, errors.New("hmac: key is required")
+	}
+	cfg := *c
+	if cfg.Hash == nil {
+		cfg.Hash = sha256.New
+	}
+	if cfg.SignatureHeader == "" {
+		cfg.SignatureHeader = "X-Signature"
+	}
+	if cfg.TimestampHeader == "" {
+		cfg.TimestampHeader = "X-Timestamp"
+	}
+	if cfg.Template == "" {
+		cfg.Template = DefaultHMACTemplate
+	}
+	if cfg.MaxSkew <= 0 {
+		cfg.MaxSkew = 5 * time.Minute
+	}
+	if cfg.Now == nil {
+		cfg.Now = time.Now
+	}
+	return cfg, nil
+}
+
+// verify returns the reason why the signature in header does not match, or "" if it does.
+func (c *HMACConfig) verify(req *http.Request, header http.Header, body []byte) string {
+	signature := header.Get(c.SignatureHeader)
+	if signature == "" {
+		return "missing " + c.SignatureHeader + " header"
+	}
+	timestamp := header.Get(c.TimestampHeader)
+	seconds, err := strconv.ParseInt(timestamp, 10, 64)
+	if err != nil {
+		return "missing or invalid " + c.TimestampHeader + " header"
+	}
+	skew := c.Now().Sub(time.Unix(seconds, 0))
+	if skew > c.MaxSkew || skew < -c.MaxSkew {
+		return fmt.Sprintf("timestamp is %s away from the current time", skew.Abs().Round(time.Second))
+	}
+	if !hmac.Equal([]byte(signature), []byte(c.sign(req, header, timestamp, body))) {
+		return "signature mismatch"
+	}
+	return ""
+}
+
+var hmacPlaceholder = regexp.MustCompile(`\{(method|path|query|timestamp|body|body_sha256|header:[^}]+)\}`)
+
+// sign computes the encoded signature of the canonical string.
+func (c *HMACConfig) sign(req *http.Request, header http.Header, timestamp string, body []byte) string {
+	canonical := hmacPlaceholder.ReplaceAllStringFunc(c.Template, func(placeholder string) string {
+		name := placeholder[1 : len(placeholder)-1]
+		switch name {
+		case "method":
+			return req.Method
+		case "path":
+			return req.URL.EscapedPath()
+		case "query":
+			return req.URL.RawQuery
+		case "timestamp":
+			return timestamp
+		case "body":
+			return string(body)
+		case "body_sha256":
+			return hexSHA256(body)
+		}
+		return header.Get(name[len("header:"):])
+	})
+	mac := hmac.New(c.Hash, c.Key)
+	mac.Write([]byte(canonical))
+	sum := mac.Sum(nil)
+	if c.Base64 {
+		return c.Prefix + base64.StdEncoding.EncodeToString(sum)
+	}
+	return c.Prefix + hex.EncodeToString(sum)
+}
@@ -0,0 +1,182 @@
+package gorest_test
+
+import (
+	"context"
+	"crypto/hmac"
+	"crypto/sha256"
+	"crypto/sha512"
+	"encoding/base64"
+	"encoding/hex"
+	"errors"
+	"io"
+	"net/http"
+	"net/http/httptest"
+	"strconv"
+	"strings"
+	"sync/atomic"
+	"time"
+
+	. "github.com/onsi/ginkgo/v2"
+	. "github.com/onsi/gomega"
+
+	"gorest/gorest"
+)
+
+var _ = Describe("HMAC", func() {
+	now := time.Unix(1700000000, 0)
+	clock := func() time.Time { return now }
+	sign := func(key, message string) string {
+		mac := hmac.New(sha256.New, []byte(key))
+		mac.Write([]byte(message))
+		return hex.EncodeToString(mac.Sum(nil))
+	}
+
+	Describe("HMACSignerMiddleware", func() {
+		var seen *http.Request
+		var seenBody []byte
+		next := func(req *http.Request) (*http.Response, error) {
+			seen = req
+			seenBody, _ = io.ReadAll(req.Body)
+			return &http.Response{StatusCode: 200, Body: http.NoBody}, nil
+		}
+
+		It("should sign the method, path, timestamp and body", func() {
+			req, _ := http.NewRequest("POST", "http://example.com/hooks/order?x=1", strings.NewReader(`{"id":1}`))
+			_, err := gorest.HMACSignerMiddleware(&gorest.HMACConfig{Key: []byte("secret"), Now: clock})(next)(req)
+			Expect(err).NotTo(HaveOccurred())
+			Expect(seen.Header.Get("X-Timestamp")).To(Equal("1700000000"))
+			Expect(seen.Header.Get("X-Signature")).To(Equal(sign("secret", "POST\n/hooks/order\n1700000000\n{\"id\":1}")))
+			Expect(string(seenBody)).To(Equal(`{"id":1}`))
+			Expect(req.Header.Get("X-Signature")).To(BeEmpty())
+		})
+
+		It("should honor a custom template, algorithm, headers and encoding", func() {
+			req, _ := http.NewRequest("PUT", "http://example.com/items?b=2", strings.NewReader("payload"))
+			req.Header.Set("X-Tenant", "acme")
+			_, err := gorest.HMACSignerMiddleware(&gorest.HMACConfig{
+				Key:             []byte("secret"),
+				Hash:            sha512.New,
+				SignatureHeader: "X-Hub-Signature",
+				TimestampHeader: "X-Hub-Time",
+				Template:        "{timestamp}.{query}.{header:X-Tenant}.{body_sha256}",
+				Prefix:          "sha512=",
+				Base64:          true,
+				Now:             clock,
+			})(next)(req)
+			Expect(err).NotTo(HaveOccurred())
+
+			digest := sha256.Sum256([]byte("payload"))
+			mac := hmac.New(sha512.New, []byte("secret"))
+			mac.Write([]byte("1700000000.b=2.acme." + hex.EncodeToString(digest[:])))
+			Expect(seen.Header.Get("X-Hub-Time")).To(Equal("1700000000"))
+			Expect(seen.Header.Get("X-Hub-Signature")).To(Equal("sha512=" + base64.StdEncoding.EncodeToString(mac.Sum(nil))))
+		})
+
+		It("should re-sign every retry attempt", func() {
+			var calls int32
+			var timestamps []string
+			server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+				body, _ := io.ReadAll(r.Body)
+				ts := r.Header.Get("X-Timestamp")
+				timestamps = append(timestamps, ts)
+				if r.Header.Get("X-Signature") != sign("secret", "PUT\n/hook\n"+ts+"\n"+string(body)) {
+					w.WriteHeader(http.StatusUnauthorized)
+					return
+				}
+				if atomic.AddInt32(&calls, 1) == 1 {
+					w.WriteHeader(http.StatusServiceUnavailable)
+				}
+			}))
+			defer server.Close()
+
+			var tick int64
+			client := gorest.NewClient(gorest.WithMiddlewares(
+				gorest.RetryMiddlewareWithConfig(&gorest.RetryConfig{Attempts: 2, Backoff: gorest.ConstantBackoff(0)}),
+				gorest.HMACSignerMiddleware(&gorest.HMACConfig{
+					Key: []byte("secret"),
+					Now: func() time.Time { return now.Add(time.Duration(atomic.AddInt64(&tick, 1)) * time.Second) },
+				}),
+			))
+			resp, err := client.Do(context.Background(), gorest.NewRequest("PUT", server.URL+"/hook").WithBody([]byte("data")))
+			Expect(err).NotTo(HaveOccurred())
+			Expect(resp.StatusCode).To(Equal(200))
+			Expect(timestamps).To(Equal([]string{"1700000001", "1700000002"}))
+		})
+	})
+
+	It("should reject a missing config or key", func() {
+		next := func(req *http.Request) (*http.Response, error) {
+			Fail("the request should not be sent")
+			return nil, nil
+		}
+		req, _ := http.NewRequest("GET", "http://example.com/status", nil)
+		_, err := gorest.HMACSignerMiddleware(nil)(next)(req)
+		Expect(err).To(MatchError("hmac: config is required"))
+		_, err = gorest.HMACSignerMiddleware(&gorest.HMACConfig{})(next)(req)
+		Expect(err).To(MatchError("hmac: key is required"))
+		_, err = gorest.HMACVerifierMiddleware(nil)(next)(req)
+		Expect(err).To(MatchError("hmac: config is required"))
+		_, err = gorest.HMACVerifierMiddleware(&gorest.HMACConfig{Key: []byte{}})(next)(req)
+		Expect(err).To(MatchError("hmac: key is required"))
+	})
+
+	Describe("HMACVerifierMiddleware", func() {
+		var (
+			signature string
+			timestamp string
+			server    *httptest.Server
+			client    *gorest.Client
+		)
+
+		BeforeEach(func() {
+			timestamp = "1700000000"
+			signature = sign("secret", "GET\n/status\n1700000000\nok")
+			server = httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+				if signature != "" {
+					w.Header().Set("X-Signature", signature)
+				}
+				w.Header().Set("X-Timestamp", timestamp)
+				_, _ = w.Write([]byte("ok"))
+			}))
+			client = gorest.NewClient(gorest.WithMiddlewares(
+				gorest.HMACVerifierMiddleware(&gorest.HMACConfig{Key: []byte("secret"), MaxSkew: time.Minute, Now: clock}),
+			))
+		})
+
+		AfterEach(func() {
+			server.Close()
+		})
+
+		It("should accept a correctly signed response", func() {
+			resp, err := client.Do(context.Background(), gorest.NewRequest("GET", server.URL+"/status"))
+			Expect(err).NotTo(HaveOccurred())
+			body, _ := resp.Bytes()
+			Expect(string(body)).To(Equal("ok"))
+		})
+
+		It("should reject a wrong signature", func() {
+			signature = sign("other", "GET\n/status\n1700000000\nok")
+			_, err := client.Do(context.Background(), gorest.NewRequest("GET", server.URL+"/status"))
+			Expect(errors.Is(err, gorest.ErrInvalidSignature)).To(BeTrue())
+			var sigErr *gorest.SignatureError
+			Expect(errors.As(err, &sigErr)).To(BeTrue())
+			Expect(sigErr.Reason).To(Equal("signature mismatch"))
+			Expect(sigErr.StatusCode).To(Equal(200))
+		})
+
+		It("should reject a missing signature", func() {
+			signature = ""
+			_, err := client.Do(context.Background(), gorest.NewRequest("GET", server.URL+"/status"))
+			Expect(errors.Is(err, gorest.ErrInvalidSignature)).To(BeTrue())
+			Expect(err.Error()).To(ContainSubstring("missing X-Signature header"))
+		})
+
+		It("should reject a timestamp outside the allowed skew", func() {
+			timestamp = strconv.FormatInt(now.Add(-2*time.Minute).Unix(), 10)
+			signature = sign("secret", "GET\n/status\n"+timestamp+"\nok")
+			_, err := client.Do(context.Background(), gorest.NewRequest("GET", server.URL+"/status"))
+			Expect(errors.Is(err, gorest.ErrInvalidSignature)).To(BeTrue())
+			Expect(err.Error()).To(ContainSubstring("2m0s away"))
+		})
+	})
+})
@@ -0,0 +1,109 @@
+package gorest
+
+import (
+	"io"
+	"net/http"
+	"strconv"
+	"sync"
+	"sync/atomic"
+	"time"
+)
+
+// MetricLabels identifies a series of request metrics.
+type MetricLabels struct {
+	// Host is the host (and port, if any) of the request URL.
+	Host string
+	// Method is the HTTP method.
+	Method string
+	// StatusClass is the class of the response status ("2xx" to "5xx"), or "error" if no response was received.
+	// It is empty when reporting requests in flight.
+	StatusClass string
+	// Route is the URL template of the request (see URLTemplate), or empty if it had no path parameters.
+	Route string
+}
+
+// RequestMetrics describes a finished request.
+type RequestMetrics struct {
+	// Duration is the time until the response headers were received, or until the request failed.
+	Duration time.Duration
+	// RequestBytes is the size of the request body read while sending the request.
+	RequestBytes int64
+	// ResponseBytes is the size of the response body read before it was closed.
+	ResponseBytes int64
+	// Retries is the number of attempts after the first one made by RetryMiddleware.
+	Retries int
+}
+
+// MetricsRecorder collects the metrics reported by the MetricsMiddleware. Implementations must be safe for concurrent use.
+// PrometheusRecorder is an implementation exposing the metrics in the Prometheus text format.
+type MetricsRecorder interface {
+	// InFlight adds delta to the number of requests in flight.
+	InFlight(labels MetricLabels, delta int)
+	// Observe records a finished request.
+	Observe(labels MetricLabels, metrics RequestMetrics)
+}
+
+// MetricsMiddleware returns a middleware that reports every request to recorder.
+// A request is in flight, and is observed, until its response body is closed so that the response size is known.
+// Place it before RetryMiddleware in WithMiddlewares so that retries are counted within a single request.
+func MetricsMiddleware(recorder MetricsRecorder) Middleware {
+	return func(next RoundTripFunc) RoundTripFunc {
+		return func(req *http.Request) (*http.Response, error) {
+			labels := MetricLabels{Host: req.URL.Host, Method: req.Method, Route: URLTemplate(req)}
+			recorder.InFlight(labels, 1)
+
+			var retries atomic.Int32
+			measured := req.WithContext(withAttemptHook(req.Context(), func(attemptReq *http.Request, attempt int) (*http.Request, func(*http.Response, error)) {
+				if attempt > 1 {
+					retries.Add(1)
+				}
+				return attemptReq, func(*http.Response, error) {}
+			}))
+			sent := &countingReadCloser{ReadCloser: req.Body}
+			if req.Body != nil && req.Body != http.NoBody {
+				measured.Body = sent
+			}
+
+			start := time.Now()
+			resp, err := next(measured)
+			duration := time.Since(start)
+			finish := func(statusClass string, received int64) {
+				recorder.InFlight(labels, -1)
+				observed := labels
+				observed.StatusClass = statusClass
+				recorder.Observe(observed, RequestMetrics{
+					Duration:      duration,
+					RequestBytes:  sent.n.Load(),
+					ResponseBytes: received,
+					Retries:       int(retries.Load()),
+				})
+			}
+			if err != nil {
+				finish("error", 0)
+				return nil, err
+			}
+			statusClass := strconv.Itoa(resp.StatusCode/100) + "xx"
+			if resp.Body == nil {
+				finish(statusClass, 0)
+				return resp, nil
+			}
+			received := &countingReadCloser{ReadCloser: resp.Body}
+			resp.Body = &releaseOnClose{ReadCloser: received, release: sync.OnceFunc(func() {
+				finish(statusClass, received.n.Load())
+			})}
+			return resp, nil
+		}
+	}
+}
+
+// countingReadCloser counts the bytes read from the underlying ReadCloser.
+type countingReadCloser struct {
+	io.ReadCloser
+	n atomic.Int64
+}
+
+func (c *countingReadCloser) Read(p []byte) (int, error) {
+	n, err := c.ReadCloser.Read(p)
+	c.n.Add(int64(n))
+	return n, err
+}
@@ -0,0 +1,129 @@
+package gorest_test
+
+import (
+	"context"
+	"errors"
+	"io"
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"sync"
+	"sync/atomic"
+
+	. "github.com/onsi/ginkgo/v2"
+	. "github.com/onsi/gomega"
+
+	"gorest/gorest"
+)
+
+// fakeMetricsRecorder keeps everything reported by the MetricsMiddleware.
+type fakeMetricsRecorder struct {
+	mu       sync.Mutex
+	inFlight map[gorest.MetricLabels]int
+	observed []gorest.MetricLabels
+	metrics  []gorest.RequestMetrics
+}
+
+func (f *fakeMetricsRecorder) InFlight(labels gorest.MetricLabels, delta int) {
+	f.mu.Lock()
+	defer f.mu.Unlock()
+	if f.inFlight == nil {
+		f.inFlight = make(map[gorest.MetricLabels]int)
+	}
+	f.inFlight[labels] += delta
+}
+
+func (f *fakeMetricsRecorder) Observe(labels gorest.MetricLabels, metrics gorest.RequestMetrics) {
+	f.mu.Lock()
+	defer f.mu.Unlock()
+	f.observed = append(f.observed, labels)
+	f.metrics = append(f.metrics, metrics)
+}
+
+var _ = Describe("MetricsMiddleware", func() {
+	var (
+		recorder *fakeMetricsRecorder
+		calls    int32
+		server   *httptest.Server
+	)
+
+	BeforeEach(func() {
+		recorder = &fakeMetricsRecorder{}
+		calls = 0
+		server = httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+			_, _ = io.Copy(io.Discard, r.Body)
+			if r.URL.Path == "/flaky" && atomic.AddInt32(&calls, 1) < 3 {
+				w.WriteHeader(http.StatusServiceUnavailable)
+				return
+			}
+			if r.URL.Path == "/missing" {
+				w.WriteHeader(http.StatusNotFound)
+			}
+			_, _ = w.Write([]byte("hello"))
+		}))
+	})
+
+	AfterEach(func() {
+		server.Close()
+	})
+
+	It("should record a request with its sizes and route", func() {
+		client := gorest.NewClient(gorest.WithBaseURL(server.URL), gorest.WithMiddlewares(gorest.MetricsMiddleware(recorder)))
+		_, err := client.Do(context.Background(), gorest.NewRequest("POST", "/users/{id}").WithPathParam("id", "7").WithBody([]byte("payload")))
+		Expect(err).NotTo(HaveOccurred())
+
+		host := strings.TrimPrefix(server.URL, "http://")
+		Expect(recorder.observed).To(Equal([]gorest.MetricLabels{{Host: host, Method: "POST", StatusClass: "2xx", Route: "/users/{id}"}}))
+		Expect(recorder.metrics[0].RequestBytes).To(Equal(int64(7)))
+		Expect(recorder.metrics[0].ResponseBytes).To(Equal(int64(5)))
+		Expect(recorder.metrics[0].Retries).To(BeZero())
+		Expect(recorder.metrics[0].Duration).To(BeNumerically(">", 0))
+		Expect(recorder.inFlight).To(Equal(map[gorest.MetricLabels]int{{Host: host, Method: "POST", Route: "/users/{id}"}: 0}))
+	})
+
+	It("should label by status class", func() {
+		client := gorest.NewClient(gorest.WithMiddlewares(gorest.MetricsMiddleware(recorder)))
+		_, err := client.Do(context.Background(), gorest.NewRequest("GET", server.URL+"/missing"))
+		Expect(err).NotTo(HaveOccurred())
+		Expect(recorder.observed[0].StatusClass).To(Equal("4xx"))
+		Expect(recorder.observed[0].Route).To(BeEmpty())
+	})
+
+	It("should keep a streamed request in flight until its body is closed", func() {
+		client := gorest.NewClient(gorest.WithMiddlewares(gorest.MetricsMiddleware(recorder)))
+		resp, err := client.DoStream(context.Background(), gorest.NewRequest("GET", server.URL))
+		Expect(err).NotTo(HaveOccurred())
+		Expect(recorder.observed).To(BeEmpty())
+		for _, n := range recorder.inFlight {
+			Expect(n).To(Equal(1))
+		}
+		_, _ = io.ReadAll(resp.Body)
+		Expect(resp.Body.Close()).To(Succeed())
+		Expect(recorder.observed).To(HaveLen(1))
+		Expect(recorder.metrics[0].ResponseBytes).To(Equal(int64(5)))
+	})
+
+	It("should count retries", func() {
+		client := gorest.NewClient(gorest.WithMiddlewares(
+			gorest.MetricsMiddleware(recorder),
+			gorest.RetryMiddlewareWithConfig(&gorest.RetryConfig{Attempts: 3, Backoff: gorest.ConstantBackoff(0)}),
+		))
+		_, err := client.Do(context.Background(), gorest.NewRequest("PUT", server.URL+"/flaky").WithBody([]byte("abc")))
+		Expect(err).NotTo(HaveOccurred())
+		Expect(recorder.observed).To(HaveLen(1))
+		Expect(recorder.metrics[0].Retries).To(Equal(2))
+		Expect(recorder.metrics[0].RequestBytes).To(Equal(int64(3)))
+	})
+
+	It("should record transport errors", func() {
+		failing := func(next gorest.RoundTripFunc) gorest.RoundTripFunc {
+			return func(req *http.Request) (*http.Response, error) {
+				return nil, errors.New("connection refused")
+			}
+		}
+		client := gorest.NewClient(gorest.WithMiddlewares(gorest.MetricsMiddleware(recorder), failing))
+		_, err := client.Do(context.Background(), gorest.NewRequest("GET", server.URL))
+		Expect(err).To(HaveOccurred())
+		Expect(recorder.observed[0].StatusClass).To(Equal("error"))
+	})
+})
@@ -24,63 +24,16 @@ func ChainMiddlewares(final RoundTripFunc, mws ...Middleware) RoundTripFunc {
 
 // RetryMiddleware returns a middleware that retries a request for a total of 'attempts' times (including the first attempt)
 // if errors occur or if a retryable HTTP status is received. The retryDelay is the wait time between attempts.
+// Requests are retried according to DefaultRetryPolicy, so non-idempotent requests are only retried on 429.
 // Note: The request body is fully buffered in memory for retry purposes.
 func RetryMiddleware(attempts int, retryDelay time.Duration) Middleware {
-	return func(next RoundTripFunc) RoundTripFunc {
-		return func(req *http.Request) (*http.Response, error) {
-			var bodyBytes []byte
-			var err error
-			if req.Body != nil {
-				bodyBytes, err = io.ReadAll(req.Body)
-				if err != nil {
-					return nil, err
-				}
-				req.Body = io.NopCloser(bytes.NewReader(bodyBytes))
-			}
-
-			var resp *http.Response
-			for i := 0; i < attempts; i++ {
-				if req.Context().Err() != nil {
-					return nil, req.Context().Err()
-				}
-
-				// Clone the request for each attempt.
-				reqAttempt := req.Clone(req.Context())
-				if req.Body != nil {
-					reqAttempt.Body = io.NopCloser(bytes.NewReader(bodyBytes))
-				}
-
-				if i > 0 {
-					time.Sleep(retryDelay)
-				}
-
-				resp, err = next(reqAttempt)
-				if err != nil {
-					continue
-				}
-				if resp.StatusCode == 429 {
-					if retryAfter := resp.Header.Get("Retry-After"); retryAfter != "" {
-						receivedAt := time.Now()
-						if delay, err := ParseRetryAfter(retryAfter, receivedAt); err == nil {
-							DrainAndClose(resp)
-							time.Sleep(delay)
-							continue
-						}
-					}
-				} else if resp.StatusCode >= 500 {
-					DrainAndClose(resp)
-					time.Sleep(retryDelay)
-					continue
-				}
-				return resp, nil
-			}
-			// After all attempts
-			if err != nil {
-				return nil, fmt.Errorf("all retry attempts failed: %w", err)
-			}
-			return nil, fmt.Errorf("all retry attempts exhausted, last status: %d", resp.StatusCode)
-		}
+	if attempts <= 0 {
+		attempts = 1
 	}
+	return RetryMiddlewareWithConfig(&RetryConfig{
+		Attempts: attempts,
+		Backoff:  ConstantBackoff(retryDelay),
+	})
 }
 
 // LoggingConfig configures the LoggingMiddleware.
@@ -95,6 +48,7 @@ type LoggingConfig struct {
 }
 
 // LoggingMiddlewareWithConfig returns a middleware that logs the HTTP request and response using the provided logger and config.
+// It is meant for debugging; use SlogMiddleware for structured logs.
 // Warning: Dumping full HTTP messages may include sensitive data.
 func LoggingMiddlewareWithConfig(logger io.Writer, config *LoggingConfig) Middleware {
 	// Set default config if nil.
@@ -205,6 +159,34 @@ func LoggingMiddleware(logger io.Writer, redactor ...func(string) string) Middle
 	})
 }
 
+// bufferRequestBody reads the full request body so that it can be replayed with cloneWithBody.
+// It returns nil if the request has no body.
+func bufferRequestBody(req *http.Request) ([]byte, error) {
+	if req.Body == nil || req.Body == http.NoBody {
+		return nil, nil
+	}
+	bodyBytes, err := io.ReadAll(req.Body)
+	if err != nil {
+		return nil, err
+	}
+	_ = req.Body.Close()
+	req.Body = io.NopCloser(bytes.NewReader(bodyBytes))
+	return bodyBytes, nil
+}
+
+// cloneWithBody clones req with a fresh reader over the buffered body.
+func cloneWithBody(req *http.Request, bodyBytes []byte) *http.Request {
+	clone := req.Clone(req.Context())
+	if bodyBytes != nil {
+		clone.Body = io.NopCloser(bytes.NewReader(bodyBytes))
+		clone.GetBody = func() (io.ReadCloser, error) {
+			return io.NopCloser(bytes.NewReader(bodyBytes)), nil
+		}
+		clone.ContentLength = int64(len(bodyBytes))
+	}
+	return clone
+}
+
 // DrainAndClose reads the remaining data from resp.Body and closes it.
 func DrainAndClose(resp *http.Response) {
 	if resp.Body != nil {
@@ -0,0 +1,193 @@
+package gorest
+
+import (
+	"fmt"
+	"io"
+	"mime/multipart"
+	"net/textproto"
+	"os"
+	"path/filepath"
+	"sort"
+	"strings"
+	"sync"
+)
+
+// MultipartPart is a single part of a multipart/form-data body streamed with Request.WithMultipartStream.
+// A part is a file if it has a Reader or a FilePath, and a regular form field with Value otherwise.
+type MultipartPart struct {
+	// FieldName is the form field name of the part.
+	FieldName string
+	// Value is the content of a regular form field.
+	Value string
+	// FileName is the file name sent for file parts. It defaults to the base name of FilePath.
+	FileName string
+	// ContentType is the part's Content-Type. File parts default to application/octet-stream.
+	ContentType string
+	// Reader provides the content of a file part. It is read once, while the request is sent.
+	Reader io.Reader
+	// FilePath is the path of a file whose content is sent, opened while the request is sent.
+	FilePath string
+}
+
+// FormField returns a regular form field part.
+func FormField(name, value string) MultipartPart {
+	return MultipartPart{FieldName: name, Value: value}
+}
+
+// FileFromPath returns a file part whose content is read from the file at filePath.
+func FileFromPath(name, filePath string) MultipartPart {
+	return MultipartPart{FieldName: name, FilePath: filePath}
+}
+
+// FileFromReader returns a file part whose content is read from r, with a custom file name and content type.
+func FileFromReader(name, fileName, contentType string, r io.Reader) MultipartPart {
+	return MultipartPart{FieldName: name, FileName: fileName, ContentType: contentType, Reader: r}
+}
+
+func (p MultipartPart) isFile() bool {
+	return p.Reader != nil || p.FilePath != ""
+}
+
+// WithMultipartStream sets a multipart/form-data body that is written through an io.Pipe while the request
+// is sent, so file contents are never buffered in memory. Parts are written in the given order.
+// File paths are checked when this method is called; any error is stored in the Request.
+// Note: Readers are consumed by the first send, and middleware that buffers bodies (such as RetryMiddleware)
+// reads the whole body into memory.
+func (r *Request) WithMultipartStream(parts ...MultipartPart) *Request {
+	for _, part := range parts {
+		if part.FilePath == "" || part.Reader != nil {
+			continue
+		}
+		if _, err := os.Stat(part.FilePath); err != nil {
+			r.buildErr = err
+			return r
+		}
+	}
+	writer := multipart.NewWriter(io.Discard)
+	r.multipartParts = append([]MultipartPart(nil), parts...)
+	r.multipartBoundary = writer.Boundary()
+	r.isMultipart = true
+	r.WithHeader("Content-Type", writer.FormDataContentType())
+	return r
+}
+
+// streamMultipart returns a reader producing the multipart body for parts.
+// The body is written by a goroutine started on the first Read, which stops when the reader is closed.
+// Until then no file is opened, so a request that is built but never sent leaks nothing.
+func streamMultipart(boundary string, parts []MultipartPart) io.ReadCloser {
+	return &multipartBody{boundary: boundary, parts: parts}
+}
+
+// multipartBody lazily pipes the output of writeMultipart.
+type multipartBody struct {
+	boundary string
+	parts    []MultipartPart
+
+	mu     sync.Mutex
+	pr     *io.PipeReader
+	closed bool
+}
+
+func (b *multipartBody) Read(p []byte) (int, error) {
+	b.mu.Lock()
+	if b.closed {
+		b.mu.Unlock()
+		return 0, io.ErrClosedPipe
+	}
+	if b.pr == nil {
+		pr, pw := io.Pipe()
+		b.pr = pr
+		go func() {
+			pw.CloseWithError(writeMultipart(pw, b.boundary, b.parts))
+		}()
+	}
+	pr := b.pr
+	b.mu.Unlock()
+	return pr.Read(p)
+}
+
+func (b *multipartBody) Close() error {
+	b.mu.Lock()
+	defer b.mu.Unlock()
+	b.closed = true
+	if b.pr == nil {
+		return nil
+	}
+	return b.pr.Close()
+}
+
+func writeMultipart(w io.Writer, boundary string, parts []MultipartPart) error {
+	writer := multipart.NewWriter(w)
+	if err := writer.SetBoundary(boundary); err != nil {
+		return err
+	}
+	for _, part := range parts {
+		if err := writeMultipartPart(writer, part); err != nil {
+			return fmt.Errorf("multipart field %q: %w", part.FieldName, err)
+		}
+	}
+	return writer.Close()
+}
+
+func writeMultipartPart(writer *multipart.Writer, part MultipartPart) (retErr error) {
+	header := make(textproto.MIMEHeader)
+	if !part.isFile() {
+		header.Set("Content-Disposition", fmt.Sprintf(`form-data; name="%s"`, escapeQuotes(part.FieldName)))
+		if part.ContentType != "" {
+			header.Set("Content-Type", part.ContentType)
+		}
+		pw, err := writer.CreatePart(header)
+		if err != nil {
+			return err
+		}
+		_, err = io.WriteString(pw, part.Value)
+		return err
+	}
+
+	src := part.Reader
+	fileName := part.FileName
+	if src == nil {
+		file, err := os.Open(part.FilePath)
+		if err != nil {
+			return err
+		}
+		defer func() {
+			if closeErr := file.Close(); closeErr != nil && retErr == nil {
+				retErr = closeErr
+			}
+		}()
+		src = file
+		if fileName == "" {
+			fileName = filepath.Base(part.FilePath)
+		}
+	}
+	contentType := part.ContentType
+	if contentType == "" {
+		contentType = "application/octet-stream"
+	}
+	header.Set("Content-Disposition",
+		fmt.Sprintf(`form-data; name="%s"; filename="%s"`, escapeQuotes(part.FieldName), escapeQuotes(fileName)))
+	header.Set("Content-Type", contentType)
+	pw, err := writer.CreatePart(header)
+	if err != nil {
+		return err
+	}
+	_, err = io.Copy(pw, src)
+	return err
+}
+
+var quoteEscaper = strings.NewReplacer("\\", "\\\\", `"`, "\\\"")
+
+func escapeQuotes(s string) string {
+	return quoteEscaper.Replace(s)
+}
+
+// sortedKeys returns the keys of m in sorted order.
+func sortedKeys(m map[string]string) []string {
+	keys := make([]string, 0, len(m))
+	for k := range m {
+		keys = append(keys, k)
+	}
+	sort.Strings(keys)
+	return keys
+}
@@ -0,0 +1,153 @@
+package gorest_test
+
+import (
+	"context"
+	"io"
+	"mime"
+	"mime/multipart"
+	"net/http"
+	"net/http/httptest"
+	"os"
+	"path/filepath"
+	"strings"
+	"sync/atomic"
+
+	. "github.com/onsi/ginkgo/v2"
+	. "github.com/onsi/gomega"
+
+	"gorest/gorest"
+)
+
+// receivedPart describes a multipart part as seen by the test server.
+type receivedPart struct {
+	Name        string
+	FileName    string
+	ContentType string
+	Content     string
+}
+
+// readParts parses a multipart body in order.
+func readParts(contentType string, body io.Reader) []receivedPart {
+	_, params, err := mime.ParseMediaType(contentType)
+	Expect(err).NotTo(HaveOccurred())
+	reader := multipart.NewReader(body, params["boundary"])
+	var parts []receivedPart
+	for {
+		part, err := reader.NextPart()
+		if err == io.EOF {
+			return parts
+		}
+		Expect(err).NotTo(HaveOccurred())
+		content, err := io.ReadAll(part)
+		Expect(err).NotTo(HaveOccurred())
+		parts = append(parts, receivedPart{
+			Name:        part.FormName(),
+			FileName:    part.FileName(),
+			ContentType: part.Header.Get("Content-Type"),
+			Content:     string(content),
+		})
+	}
+}
+
+// readFunc is an adapter to allow the use of ordinary functions as io.Reader.
+type readFunc func(p []byte) (int, error)
+
+func (f readFunc) Read(p []byte) (int, error) {
+	return f(p)
+}
+
+var _ = Describe("Multipart", func() {
+	var filePath string
+
+	BeforeEach(func() {
+		tmpFile, err := os.CreateTemp("", "upload")
+		Expect(err).NotTo(HaveOccurred())
+		_, err = tmpFile.WriteString("file content")
+		Expect(err).NotTo(HaveOccurred())
+		Expect(tmpFile.Close()).To(Succeed())
+		filePath = tmpFile.Name()
+	})
+
+	AfterEach(func() {
+		os.Remove(filePath)
+	})
+
+	It("should stream parts in order to the server", func() {
+		var parts []receivedPart
+		var contentLength int64
+		server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+			contentLength = r.ContentLength
+			parts = readParts(r.Header.Get("Content-Type"), r.Body)
+		}))
+		defer server.Close()
+
+		req := gorest.NewRequest("POST", server.URL).WithMultipartStream(
+			gorest.FormField("z", "last letter"),
+			gorest.FileFromReader("data", "report.csv", "text/csv", strings.NewReader("a,b\n1,2\n")),
+			gorest.FileFromPath("attachment", filePath),
+			gorest.FormField("a", "first letter"),
+		)
+		_, err := gorest.NewClient().Do(context.Background(), req)
+		Expect(err).NotTo(HaveOccurred())
+		Expect(contentLength).To(Equal(int64(-1)))
+		Expect(parts).To(Equal([]receivedPart{
+			{Name: "z", Content: "last letter"},
+			{Name: "data", FileName: "report.csv", ContentType: "text/csv", Content: "a,b\n1,2\n"},
+			{Name: "attachment", FileName: filepath.Base(filePath), ContentType: "application/octet-stream", Content: "file content"},
+			{Name: "a", Content: "first letter"},
+		}))
+	})
+
+	It("should store an error for missing files", func() {
+		req := gorest.NewRequest("POST", "http://example.com").WithMultipartStream(gorest.FileFromPath("f", "nonexistent_file.txt"))
+		_, err := req.BuildHTTPRequest()
+		Expect(err).To(HaveOccurred())
+	})
+
+	It("should fail the request when a reader fails", func() {
+		req := gorest.NewRequest("POST", "http://example.com").WithMultipartStream(
+			gorest.FileFromReader("f", "broken.bin", "", &errorReader{}),
+		)
+		httpReq, err := req.BuildHTTPRequest()
+		Expect(err).NotTo(HaveOccurred())
+		_, err = io.ReadAll(httpReq.Body)
+		Expect(err).To(MatchError(ContainSubstring("read error")))
+	})
+
+	It("should not start streaming until the body is read", func() {
+		var reads int32
+		source := readFunc(func(p []byte) (int, error) {
+			atomic.AddInt32(&reads, 1)
+			return 0, io.EOF
+		})
+		_, err := gorest.NewRequest("BAD METHOD", "http://example.com").
+			WithMultipartStream(gorest.FileFromReader("f", "f.bin", "", source)).
+			BuildHTTPRequest()
+		Expect(err).To(HaveOccurred())
+
+		httpReq, err := gorest.NewRequest("POST", "http://example.com").
+			WithMultipartStream(gorest.FileFromReader("f", "f.bin", "", source)).
+			BuildHTTPRequest()
+		Expect(err).NotTo(HaveOccurred())
+		Consistently(func() int32 { return atomic.LoadInt32(&reads) }, "50ms").Should(BeZero())
+
+		Expect(httpReq.Body.Close()).To(Succeed())
+		_, err = httpReq.Body.Read(make([]byte, 1))
+		Expect(err).To(MatchError(io.ErrClosedPipe))
+		Expect(atomic.LoadInt32(&reads)).To(BeZero())
+	})
+
+	It("should write WithMultipartForm fields in a deterministic order", func() {
+		formFields := map[string]string{"c": "3", "a": "1", "b": "2", "d": "4"}
+		fileFields := map[string]string{"zfile": filePath, "afile": filePath}
+		for i := 0; i < 5; i++ {
+			httpReq, err := gorest.NewRequest("POST", "http://example.com").WithMultipartForm(formFields, fileFields).BuildHTTPRequest()
+			Expect(err).NotTo(HaveOccurred())
+			var names []string
+			for _, part := range readParts(httpReq.Header.Get("Content-Type"), httpReq.Body) {
+				names = append(names, part.Name)
+			}
+			Expect(names).To(Equal([]string{"a", "b", "c", "d", "afile", "zfile"}))
+		}
+	})
+})
@@ -0,0 +1,58 @@
+package gorest
+
+import (
+	"bufio"
+	"bytes"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"iter"
+)
+
+// defaultMaxLineSize is the default limit for a single NDJSON record.
+const defaultMaxLineSize = 1 << 20
+
+// ErrLineTooLong is returned by DecodeLines when a record exceeds the maximum line size.
+var ErrLineTooLong = errors.New("ndjson: line too long")
+
+// DecodeLines decodes a newline-delimited JSON (NDJSON / JSON Lines) response body incrementally,
+// yielding one value of type T per non-empty line. It is meant for bodies returned by Client.DoStream.
+// An optional maximum line size can be provided (default is 1 MiB); longer lines yield ErrLineTooLong.
+// Iteration stops at the first error, which is yielded together with the line number in its message.
+// The response body is closed when iteration finishes, including when the caller stops early.
+func DecodeLines[T any](r *Response, maxLineSize ...int) iter.Seq2[T, error] {
+	limit := defaultMaxLineSize
+	if len(maxLineSize) > 0 && maxLineSize[0] > 0 {
+		limit = maxLineSize[0]
+	}
+	return func(yield func(T, error) bool) {
+		defer func() {
+			_ = r.Close()
+		}()
+		var zero T
+		scanner := bufio.NewScanner(r.Body)
+		scanner.Buffer(make([]byte, min(4096, limit)), limit)
+		line := 0
+		for scanner.Scan() {
+			line++
+			data := bytes.TrimSpace(scanner.Bytes())
+			if len(data) == 0 {
+				continue
+			}
+			var v T
+			if err := json.Unmarshal(data, &v); err != nil {
+				yield(zero, fmt.Errorf("ndjson: line %d: %w", line, err))
+				return
+			}
+			if !yield(v, nil) {
+				return
+			}
+		}
+		if err := scanner.Err(); err != nil {
+			if errors.Is(err, bufio.ErrTooLong) {
+				err = fmt.Errorf("%w: line %d exceeds %d bytes", ErrLineTooLong, line+1, limit)
+			}
+			yield(zero, err)
+		}
+	}
+}
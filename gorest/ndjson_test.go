@@ -0,0 +1,103 @@
+package gorest_test
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"io"
+	"net/http"
+	"net/http/httptest"
+	"strings"
+
+	. "github.com/onsi/ginkgo/v2"
+	. "github.com/onsi/gomega"
+
+	"gorest/gorest"
+)
+
+type record struct {
+	N int `json:"n"`
+}
+
+var _ = Describe("DecodeLines", func() {
+	newResponse := func(body string, closed *bool) *gorest.Response {
+		return &gorest.Response{Response: &http.Response{
+			StatusCode: 200,
+			Body: &dummyReadCloser{
+				Reader:    strings.NewReader(body),
+				closeFunc: func() error { *closed = true; return nil },
+			},
+		}}
+	}
+
+	It("should decode records streamed by DoStream", func() {
+		server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+			w.Header().Set("Content-Type", "application/x-ndjson")
+			for i := 1; i <= 3; i++ {
+				_, _ = fmt.Fprintf(w, "{\"n\": %d}\n", i)
+				w.(http.Flusher).Flush()
+			}
+		}))
+		defer server.Close()
+
+		resp, err := gorest.NewClient().DoStream(context.Background(), gorest.NewRequest("GET", server.URL))
+		Expect(err).NotTo(HaveOccurred())
+		var got []int
+		for rec, err := range gorest.DecodeLines[record](resp) {
+			Expect(err).NotTo(HaveOccurred())
+			got = append(got, rec.N)
+		}
+		Expect(got).To(Equal([]int{1, 2, 3}))
+	})
+
+	It("should skip blank lines and handle CRLF line endings", func() {
+		closed := false
+		var got []int
+		for rec, err := range gorest.DecodeLines[record](newResponse("{\"n\":1}\r\n\r\n{\"n\":2}", &closed)) {
+			Expect(err).NotTo(HaveOccurred())
+			got = append(got, rec.N)
+		}
+		Expect(got).To(Equal([]int{1, 2}))
+		Expect(closed).To(BeTrue())
+	})
+
+	It("should close the body when the caller stops early", func() {
+		closed := false
+		for range gorest.DecodeLines[record](newResponse("{\"n\":1}\n{\"n\":2}\n", &closed)) {
+			break
+		}
+		Expect(closed).To(BeTrue())
+	})
+
+	It("should report malformed records with their line number", func() {
+		closed := false
+		var errs []error
+		for _, err := range gorest.DecodeLines[record](newResponse("{\"n\":1}\nnot json\n{\"n\":3}\n", &closed)) {
+			if err != nil {
+				errs = append(errs, err)
+			}
+		}
+		Expect(errs).To(HaveLen(1))
+		Expect(errs[0].Error()).To(ContainSubstring("line 2"))
+	})
+
+	It("should reject lines longer than the maximum line size", func() {
+		closed := false
+		body := "{\"n\":1}\n{\"pad\":\"" + strings.Repeat("x", 100) + "\"}\n"
+		var lastErr error
+		for _, err := range gorest.DecodeLines[record](newResponse(body, &closed), 32) {
+			lastErr = err
+		}
+		Expect(errors.Is(lastErr, gorest.ErrLineTooLong)).To(BeTrue())
+		Expect(closed).To(BeTrue())
+	})
+
+	It("should yield read errors", func() {
+		response := &gorest.Response{Response: &http.Response{Body: io.NopCloser(&errorReader{})}}
+		var lastErr error
+		for _, err := range gorest.DecodeLines[record](response) {
+			lastErr = err
+		}
+		Expect(lastErr).To(MatchError(ContainSubstring("read error")))
+	})
+})
@@ -0,0 +1,335 @@
+package gorest
+
+import (
+	"context"
+	"encoding/base64"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"net/http"
+	"net/url"
+	"strings"
+	"sync"
+	"time"
+)
+
+// Token is an OAuth2 access token.
+type Token struct {
+	// AccessToken is sent in the Authorization header.
+	AccessToken string
+	// TokenType is the type of the token, usually "Bearer".
+	TokenType string
+	// RefreshToken, if set, can be used to obtain a new access token.
+	RefreshToken string
+	// Expiry is when the access token expires. The zero value means it does not expire.
+	Expiry time.Time
+}
+
+// expired reports whether the token is expired, or will be within early.
+func (t *Token) expired(early time.Duration) bool {
+	return !t.Expiry.IsZero() && !time.Now().Add(early).Before(t.Expiry)
+}
+
+// TokenSource provides access tokens.
+type TokenSource interface {
+	Token(ctx context.Context) (*Token, error)
+}
+
+// TokenSourceFunc is an adapter to allow the use of ordinary functions as a TokenSource.
+type TokenSourceFunc func(ctx context.Context) (*Token, error)
+
+// Token calls f(ctx).
+func (f TokenSourceFunc) Token(ctx context.Context) (*Token, error) {
+	return f(ctx)
+}
+
+// StaticTokenSource returns a TokenSource that always returns the given access token.
+func StaticTokenSource(accessToken string) TokenSource {
+	token := &Token{AccessToken: accessToken, TokenType: "Bearer"}
+	return TokenSourceFunc(func(context.Context) (*Token, error) {
+		return token, nil
+	})
+}
+
+// defaultEarlyExpiry is how long before its expiry a cached token is refreshed.
+const defaultEarlyExpiry = 10 * time.Second
+
+// CachingTokenSource caches the tokens of another TokenSource until shortly before they expire.
+// Concurrent callers share a single refresh.
+type CachingTokenSource struct {
+	src         TokenSource
+	earlyExpiry time.Duration
+
+	mu      sync.Mutex
+	token   *Token
+	refresh *tokenRefresh
+}
+
+// tokenRefresh is a refresh in progress; done is closed once token and err are set.
+type tokenRefresh struct {
+	done  chan struct{}
+	token *Token
+	err   error
+}
+
+// NewCachingTokenSource wraps src so that tokens are reused until earlyExpiry before they expire.
+// An earlyExpiry of zero means 10 seconds.
+func NewCachingTokenSource(src TokenSource, earlyExpiry time.Duration) *CachingTokenSource {
+	if earlyExpiry <= 0 {
+		earlyExpiry = defaultEarlyExpiry
+	}
+	return &CachingTokenSource{src: src, earlyExpiry: earlyExpiry}
+}
+
+// Token returns the cached token, or fetches a new one if it is missing or about to expire.
+// A refresh is not cancelled when ctx is done, since other callers may be waiting for it.
+func (s *CachingTokenSource) Token(ctx context.Context) (*Token, error) {
+	s.mu.Lock()
+	if s.token != nil && !s.token.expired(s.earlyExpiry) {
+		token := s.token
+		s.mu.Unlock()
+		return token, nil
+	}
+	r := s.refresh
+	if r == nil {
+		r = &tokenRefresh{done: make(chan struct{})}
+		s.refresh = r
+		go s.fetch(context.WithoutCancel(ctx), r)
+	}
+	s.mu.Unlock()
+
+	select {
+	case <-r.done:
+		return r.token, r.err
+	case <-ctx.Done():
+		return nil, ctx.Err()
+	}
+}
+
+func (s *CachingTokenSource) fetch(ctx context.Context, r *tokenRefresh) {
+	r.token, r.err = s.src.Token(ctx)
+	if r.err == nil && r.token == nil {
+		r.err = errors.New("token source returned no token")
+	}
+	s.mu.Lock()
+	if r.err == nil {
+		s.token = r.token
+	}
+	s.refresh = nil
+	s.mu.Unlock()
+	close(r.done)
+}
+
+// Invalidate discards token if it is still the cached token, so that the next call to Token fetches a new one.
+// Passing the rejected token ensures that many requests failing with the same token cause a single refresh.
+func (s *CachingTokenSource) Invalidate(token *Token) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	if s.token == token {
+		s.token = nil
+	}
+}
+
+// BearerAuthMiddleware returns a middleware that sets an "Authorization: Bearer" header with a token from src.
+// Tokens are cached until shortly before they expire (see CachingTokenSource). If the server responds with
+// 401 Unauthorized, the token is refreshed and the request is retried once.
+// Note: The request body is fully buffered in memory so that the request can be retried.
+func BearerAuthMiddleware(src TokenSource) Middleware {
+	cache, ok := src.(*CachingTokenSource)
+	if !ok {
+		cache = NewCachingTokenSource(src, 0)
+	}
+	return func(next RoundTripFunc) RoundTripFunc {
+		return func(req *http.Request) (*http.Response, error) {
+			bodyBytes, err := bufferRequestBody(req)
+			if err != nil {
+				return nil, err
+			}
+			send := func(token *Token) (*http.Response, error) {
+				authReq := cloneWithBody(req, bodyBytes)
+				authReq.Header.Set("Authorization", "Bearer "+token.AccessToken)
+				return next(authReq)
+			}
+
+			token, err := cache.Token(req.Context())
+			if err != nil {
+				return nil, err
+			}
+			resp, err := send(token)
+			if err != nil || resp.StatusCode != http.StatusUnauthorized {
+				return resp, err
+			}
+
+			cache.Invalidate(token)
+			fresh, tokenErr := cache.Token(req.Context())
+			if tokenErr != nil || fresh.AccessToken == token.AccessToken {
+				// A new token would not help, so return the 401.
+				return resp, nil
+			}
+			DrainAndClose(resp)
+			return send(fresh)
+		}
+	}
+}
+
+// TokenError is returned when a token endpoint rejects a request (RFC 6749 section 5.2).
+type TokenError struct {
+	// Code is the error code, such as "invalid_client" or "invalid_grant".
+	Code string
+	// Description is the human-readable error description, if any.
+	Description string
+	// URI points to a page describing the error, if any.
+	URI string
+	// Err describes the response of the token endpoint.
+	Err *HTTPError
+}
+
+// Error implements the error interface.
+func (e *TokenError) Error() string {
+	if e.Code == "" {
+		return fmt.Sprintf("oauth2: token request failed: %v", e.Err)
+	}
+	msg := fmt.Sprintf("oauth2: token request failed with status %d: %s", e.Err.StatusCode, e.Code)
+	if e.Description != "" {
+		msg += ": " + e.Description
+	}
+	return msg
+}
+
+// Unwrap returns the HTTPError describing the response.
+func (e *TokenError) Unwrap() error {
+	return e.Err
+}
+
+// TokenEndpoint describes an OAuth2 token endpoint and the client credentials used with it.
+type TokenEndpoint struct {
+	// Client sends the token requests. Defaults to NewClient().
+	Client *Client
+	// URL is the token endpoint URL.
+	URL string
+	// ClientID and ClientSecret identify the client.
+	ClientID     string
+	ClientSecret string
+	// AuthInBody sends the client credentials as form parameters instead of with HTTP Basic authentication.
+	AuthInBody bool
+}
+
+// ClientCredentialsConfig configures a ClientCredentialsTokenSource.
+type ClientCredentialsConfig struct {
+	TokenEndpoint
+	// Scopes lists the requested scopes.
+	Scopes []string
+	// Params holds additional form parameters, such as "audience".
+	Params url.Values
+}
+
+// ClientCredentialsTokenSource returns a TokenSource that obtains tokens with the client credentials grant.
+// Wrap it with NewCachingTokenSource, or pass it to BearerAuthMiddleware which does so.
+func ClientCredentialsTokenSource(config *ClientCredentialsConfig) TokenSource {
+	cfg := *config
+	return TokenSourceFunc(func(ctx context.Context) (*Token, error) {
+		form := url.Values{"grant_type": {"client_credentials"}}
+		if len(cfg.Scopes) > 0 {
+			form.Set("scope", strings.Join(cfg.Scopes, " "))
+		}
+		for key, values := range cfg.Params {
+			form[key] = values
+		}
+		return cfg.TokenEndpoint.requestToken(ctx, form)
+	})
+}
+
+// RefreshTokenConfig configures a RefreshTokenSource.
+type RefreshTokenConfig struct {
+	TokenEndpoint
+	// RefreshToken is the initial refresh token.
+	RefreshToken string
+	// Scopes optionally narrows the scopes of the new access tokens.
+	Scopes []string
+}
+
+// RefreshTokenSource returns a TokenSource that obtains tokens with the refresh token grant.
+// If the token endpoint rotates refresh tokens, the latest one is used for the next refresh.
+func RefreshTokenSource(config *RefreshTokenConfig) TokenSource {
+	cfg := *config
+	var mu sync.Mutex
+	refreshToken := cfg.RefreshToken
+	return TokenSourceFunc(func(ctx context.Context) (*Token, error) {
+		mu.Lock()
+		defer mu.Unlock()
+		form := url.Values{"grant_type": {"refresh_token"}, "refresh_token": {refreshToken}}
+		if len(cfg.Scopes) > 0 {
+			form.Set("scope", strings.Join(cfg.Scopes, " "))
+		}
+		token, err := cfg.TokenEndpoint.requestToken(ctx, form)
+		if err != nil {
+			return nil, err
+		}
+		if token.RefreshToken != "" {
+			refreshToken = token.RefreshToken
+		} else {
+			token.RefreshToken = refreshToken
+		}
+		return token, nil
+	})
+}
+
+// tokenResponse is the JSON body of a successful token response (RFC 6749 section 5.1).
+type tokenResponse struct {
+	AccessToken  string `json:"access_token"`
+	TokenType    string `json:"token_type"`
+	RefreshToken string `json:"refresh_token"`
+	ExpiresIn    int64  `json:"expires_in"`
+}
+
+// requestToken posts form to the token endpoint and decodes the token response.
+func (e *TokenEndpoint) requestToken(ctx context.Context, form url.Values) (*Token, error) {
+	client := e.Client
+	if client == nil {
+		client = NewClient()
+	}
+	if e.AuthInBody {
+		form.Set("client_id", e.ClientID)
+		if e.ClientSecret != "" {
+			form.Set("client_secret", e.ClientSecret)
+		}
+	}
+	req := NewRequest(http.MethodPost, e.URL).WithFormBody(form).WithHeader("Accept", "application/json")
+	if !e.AuthInBody {
+		// RFC 6749 section 2.3.1 requires the credentials to be form-encoded before Basic encoding.
+		credentials := url.QueryEscape(e.ClientID) + ":" + url.QueryEscape(e.ClientSecret)
+		req.WithHeader("Authorization", "Basic "+base64.StdEncoding.EncodeToString([]byte(credentials)))
+	}
+
+	resp, err := client.roundTrip(ctx, req)
+	if err != nil {
+		return nil, err
+	}
+	if resp.StatusCode < 200 || resp.StatusCode >= 300 {
+		httpErr := newHTTPError(resp.Request, resp)
+		tokenErr := &TokenError{Err: httpErr}
+		var body struct {
+			Error            string `json:"error"`
+			ErrorDescription string `json:"error_description"`
+			ErrorURI         string `json:"error_uri"`
+		}
+		if json.Unmarshal(httpErr.Body, &body) == nil {
+			tokenErr.Code, tokenErr.Description, tokenErr.URI = body.Error, body.ErrorDescription, body.ErrorURI
+		}
+		return nil, tokenErr
+	}
+	defer resp.Body.Close()
+
+	var body tokenResponse
+	if err := json.NewDecoder(resp.Body).Decode(&body); err != nil {
+		return nil, fmt.Errorf("oauth2: cannot decode token response: %w", err)
+	}
+	if body.AccessToken == "" {
+		return nil, errors.New("oauth2: token response has no access_token")
+	}
+	token := &Token{AccessToken: body.AccessToken, TokenType: body.TokenType, RefreshToken: body.RefreshToken}
+	if body.ExpiresIn > 0 {
+		token.Expiry = time.Now().Add(time.Duration(body.ExpiresIn) * time.Second)
+	}
+	return token, nil
+}
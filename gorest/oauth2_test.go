@@ -0,0 +1,184 @@
+package gorest_test
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"net/http"
+	"net/http/httptest"
+	"sync"
+	"sync/atomic"
+	"time"
+
+	. "github.com/onsi/ginkgo/v2"
+	. "github.com/onsi/gomega"
+
+	"gorest/gorest"
+)
+
+var _ = Describe("OAuth2", func() {
+	var (
+		tokenRequests int32
+		forms         []map[string]string
+		tokenHandler  func(w http.ResponseWriter, n int32)
+		tokenServer   *httptest.Server
+		validToken    string
+		apiServer     *httptest.Server
+		mu            sync.Mutex
+	)
+
+	BeforeEach(func() {
+		atomic.StoreInt32(&tokenRequests, 0)
+		forms = nil
+		validToken = "token-1"
+		tokenHandler = func(w http.ResponseWriter, n int32) {
+			w.Header().Set("Content-Type", "application/json")
+			_, _ = fmt.Fprintf(w, `{"access_token":"token-%d","token_type":"Bearer","expires_in":3600}`, n)
+		}
+		tokenServer = httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+			n := atomic.AddInt32(&tokenRequests, 1)
+			Expect(r.ParseForm()).To(Succeed())
+			form := map[string]string{}
+			for key := range r.PostForm {
+				form[key] = r.PostForm.Get(key)
+			}
+			if id, secret, ok := r.BasicAuth(); ok {
+				form["basic"] = id + ":" + secret
+			}
+			mu.Lock()
+			forms = append(forms, form)
+			mu.Unlock()
+			time.Sleep(10 * time.Millisecond)
+			tokenHandler(w, n)
+		}))
+		apiServer = httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+			mu.Lock()
+			valid := validToken
+			mu.Unlock()
+			if r.Header.Get("Authorization") != "Bearer "+valid {
+				w.WriteHeader(http.StatusUnauthorized)
+				return
+			}
+			_, _ = fmt.Fprint(w, "secret data")
+		}))
+	})
+
+	AfterEach(func() {
+		tokenServer.Close()
+		apiServer.Close()
+	})
+
+	clientCredentials := func() gorest.TokenSource {
+		return gorest.ClientCredentialsTokenSource(&gorest.ClientCredentialsConfig{
+			TokenEndpoint: gorest.TokenEndpoint{URL: tokenServer.URL, ClientID: "my client", ClientSecret: "s3cr3t"},
+			Scopes:        []string{"read", "write"},
+		})
+	}
+
+	It("should fetch a token once for concurrent requests and send it as a bearer token", func() {
+		client := gorest.NewClient(gorest.WithMiddlewares(gorest.BearerAuthMiddleware(clientCredentials())))
+		reqs := make([]*gorest.Request, 5)
+		for i := range reqs {
+			reqs[i] = gorest.NewRequest("GET", apiServer.URL)
+		}
+		responses, err := client.DoGroup(context.Background(), nil, reqs...)
+		Expect(err).NotTo(HaveOccurred())
+		for _, resp := range responses {
+			Expect(resp.StatusCode).To(Equal(200))
+		}
+		Expect(atomic.LoadInt32(&tokenRequests)).To(Equal(int32(1)))
+		Expect(forms[0]).To(Equal(map[string]string{
+			"grant_type": "client_credentials",
+			"scope":      "read write",
+			"basic":      "my+client:s3cr3t",
+		}))
+	})
+
+	It("should refresh the token and retry once on 401", func() {
+		client := gorest.NewClient(gorest.WithMiddlewares(gorest.BearerAuthMiddleware(clientCredentials())))
+		resp, err := client.Do(context.Background(), gorest.NewRequest("GET", apiServer.URL))
+		Expect(err).NotTo(HaveOccurred())
+		Expect(resp.StatusCode).To(Equal(200))
+
+		mu.Lock()
+		validToken = "token-2"
+		mu.Unlock()
+		resp, err = client.Do(context.Background(), gorest.NewRequest("POST", apiServer.URL).WithBody([]byte("payload")))
+		Expect(err).NotTo(HaveOccurred())
+		Expect(resp.StatusCode).To(Equal(200))
+		Expect(atomic.LoadInt32(&tokenRequests)).To(Equal(int32(2)))
+	})
+
+	It("should return the 401 when a new token is not accepted either", func() {
+		validToken = "never"
+		client := gorest.NewClient(gorest.WithMiddlewares(gorest.BearerAuthMiddleware(clientCredentials())))
+		resp, err := client.Do(context.Background(), gorest.NewRequest("GET", apiServer.URL))
+		Expect(err).NotTo(HaveOccurred())
+		Expect(resp.StatusCode).To(Equal(http.StatusUnauthorized))
+		Expect(atomic.LoadInt32(&tokenRequests)).To(Equal(int32(2)))
+	})
+
+	It("should use and rotate refresh tokens", func() {
+		tokenHandler = func(w http.ResponseWriter, n int32) {
+			_, _ = fmt.Fprintf(w, `{"access_token":"token-%d","refresh_token":"refresh-%d","expires_in":1}`, n, n+1)
+		}
+		src := gorest.RefreshTokenSource(&gorest.RefreshTokenConfig{
+			TokenEndpoint: gorest.TokenEndpoint{URL: tokenServer.URL, ClientID: "app", AuthInBody: true},
+			RefreshToken:  "refresh-1",
+		})
+		for i := 1; i <= 2; i++ {
+			token, err := src.Token(context.Background())
+			Expect(err).NotTo(HaveOccurred())
+			Expect(token.AccessToken).To(Equal(fmt.Sprintf("token-%d", i)))
+			Expect(token.Expiry).To(BeTemporally("~", time.Now().Add(time.Second), time.Second))
+		}
+		Expect(forms).To(Equal([]map[string]string{
+			{"grant_type": "refresh_token", "refresh_token": "refresh-1", "client_id": "app"},
+			{"grant_type": "refresh_token", "refresh_token": "refresh-2", "client_id": "app"},
+		}))
+	})
+
+	It("should return a TokenError when the token endpoint rejects the request", func() {
+		tokenHandler = func(w http.ResponseWriter, _ int32) {
+			w.Header().Set("Content-Type", "application/json")
+			w.WriteHeader(http.StatusBadRequest)
+			_, _ = fmt.Fprint(w, `{"error":"invalid_client","error_description":"unknown client"}`)
+		}
+		_, err := clientCredentials().Token(context.Background())
+		var tokenErr *gorest.TokenError
+		Expect(errors.As(err, &tokenErr)).To(BeTrue())
+		Expect(tokenErr.Code).To(Equal("invalid_client"))
+		Expect(tokenErr.Description).To(Equal("unknown client"))
+		Expect(gorest.IsBadRequest(err)).To(BeTrue())
+	})
+
+	Describe("CachingTokenSource", func() {
+		It("should refresh tokens that are about to expire", func() {
+			var calls int32
+			src := gorest.NewCachingTokenSource(gorest.TokenSourceFunc(func(context.Context) (*gorest.Token, error) {
+				n := atomic.AddInt32(&calls, 1)
+				return &gorest.Token{AccessToken: fmt.Sprint(n), Expiry: time.Now().Add(5 * time.Second)}, nil
+			}), 10*time.Second)
+			first, err := src.Token(context.Background())
+			Expect(err).NotTo(HaveOccurred())
+			second, err := src.Token(context.Background())
+			Expect(err).NotTo(HaveOccurred())
+			Expect(first.AccessToken).NotTo(Equal(second.AccessToken))
+		})
+
+		It("should refresh once when the same token is invalidated several times", func() {
+			var calls int32
+			src := gorest.NewCachingTokenSource(gorest.TokenSourceFunc(func(context.Context) (*gorest.Token, error) {
+				atomic.AddInt32(&calls, 1)
+				return &gorest.Token{AccessToken: "t"}, nil
+			}), 0)
+			token, _ := src.Token(context.Background())
+			src.Invalidate(token)
+			fresh, _ := src.Token(context.Background())
+			src.Invalidate(token)
+			again, _ := src.Token(context.Background())
+			Expect(again).To(BeIdenticalTo(fresh))
+			Expect(atomic.LoadInt32(&calls)).To(Equal(int32(2)))
+		})
+	})
+})
@@ -0,0 +1,78 @@
+package gorest
+
+import (
+	"io"
+	"net/http"
+)
+
+// ProgressFunc is called as a body is transferred with the number of bytes transferred so far
+// and the total size, which is -1 when unknown.
+type ProgressFunc func(transferred, total int64)
+
+// progressReader reports the bytes read from an underlying reader.
+type progressReader struct {
+	r           io.Reader
+	total       int64
+	transferred int64
+	fn          ProgressFunc
+}
+
+func (p *progressReader) Read(b []byte) (int, error) {
+	n, err := p.r.Read(b)
+	if n > 0 {
+		p.transferred += int64(n)
+		p.fn(p.transferred, p.total)
+	}
+	return n, err
+}
+
+// progressReadCloser is a progressReader that closes the underlying body.
+type progressReadCloser struct {
+	progressReader
+	closer io.Closer
+}
+
+func (p *progressReadCloser) Close() error {
+	return p.closer.Close()
+}
+
+func newProgressReadCloser(rc io.ReadCloser, total int64, fn ProgressFunc) io.ReadCloser {
+	if total <= 0 {
+		total = -1
+	}
+	return &progressReadCloser{progressReader: progressReader{r: rc, total: total, fn: fn}, closer: rc}
+}
+
+// WithUploadProgress sets a callback reporting how much of the request body has been sent.
+// The total is the body length when known (byte slices, JSON and form bodies) and -1 otherwise.
+// Note: Middleware that buffers bodies (such as RetryMiddleware) reads the whole body before sending it.
+func (r *Request) WithUploadProgress(fn ProgressFunc) *Request {
+	r.uploadProgress = fn
+	return r
+}
+
+// wrapUploadProgress wraps the body of httpReq to report upload progress.
+func wrapUploadProgress(httpReq *http.Request, fn ProgressFunc) {
+	if httpReq.Body == nil || httpReq.Body == http.NoBody {
+		return
+	}
+	total := httpReq.ContentLength
+	httpReq.Body = newProgressReadCloser(httpReq.Body, total, fn)
+	if getBody := httpReq.GetBody; getBody != nil {
+		httpReq.GetBody = func() (io.ReadCloser, error) {
+			body, err := getBody()
+			if err != nil {
+				return nil, err
+			}
+			return newProgressReadCloser(body, total, fn), nil
+		}
+	}
+}
+
+// WithDownloadProgress wraps the response body so that reading it, for example with SaveToFile or StreamChunks,
+// reports the bytes received against the Content-Length (-1 when unknown). It returns the Response for chaining.
+// Use it with Client.DoStream; bodies returned by Client.Do are already buffered in memory.
+func (r *Response) WithDownloadProgress(fn ProgressFunc) *Response {
+	r.Body = newProgressReadCloser(r.Body, r.ContentLength, fn)
+	return r
+}
@@ -0,0 +1,93 @@
+package gorest_test
+
+import (
+	"context"
+	"io"
+	"net/http"
+	"net/http/httptest"
+	"os"
+	"path/filepath"
+	"strconv"
+	"strings"
+
+	. "github.com/onsi/ginkgo/v2"
+	. "github.com/onsi/gomega"
+
+	"gorest/gorest"
+)
+
+var _ = Describe("Progress", func() {
+	It("should report upload progress against the body length", func() {
+		var received int
+		server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+			b, _ := io.ReadAll(r.Body)
+			received = len(b)
+		}))
+		defer server.Close()
+
+		var lastSent, lastTotal int64
+		payload := []byte(strings.Repeat("x", 100000))
+		req := gorest.NewRequest("PUT", server.URL).WithBody(payload).WithUploadProgress(func(sent, total int64) {
+			Expect(sent).To(BeNumerically(">", lastSent))
+			lastSent, lastTotal = sent, total
+		})
+		_, err := gorest.NewClient().Do(context.Background(), req)
+		Expect(err).NotTo(HaveOccurred())
+		Expect(received).To(Equal(len(payload)))
+		Expect(lastSent).To(Equal(int64(len(payload))))
+		Expect(lastTotal).To(Equal(int64(len(payload))))
+	})
+
+	It("should report an unknown total for streamed bodies", func() {
+		var lastTotal int64
+		req := gorest.NewRequest("POST", "http://example.com").
+			WithMultipartStream(gorest.FormField("a", "b")).
+			WithUploadProgress(func(_, total int64) { lastTotal = total })
+		httpReq, err := req.BuildHTTPRequest()
+		Expect(err).NotTo(HaveOccurred())
+		_, err = io.ReadAll(httpReq.Body)
+		Expect(err).NotTo(HaveOccurred())
+		Expect(lastTotal).To(Equal(int64(-1)))
+	})
+
+	It("should report download progress while saving to a file", func() {
+		content := strings.Repeat("y", 50000)
+		server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+			w.Header().Set("Content-Length", strconv.Itoa(len(content)))
+			_, _ = io.WriteString(w, content)
+		}))
+		defer server.Close()
+
+		resp, err := gorest.NewClient().DoStream(context.Background(), gorest.NewRequest("GET", server.URL))
+		Expect(err).NotTo(HaveOccurred())
+
+		var lastReceived, lastTotal int64
+		calls := 0
+		filePath := filepath.Join(GinkgoT().TempDir(), "download")
+		err = resp.WithDownloadProgress(func(received, total int64) {
+			calls++
+			lastReceived, lastTotal = received, total
+		}).SaveToFile(filePath)
+		Expect(err).NotTo(HaveOccurred())
+		Expect(calls).To(BeNumerically(">", 0))
+		Expect(lastReceived).To(Equal(int64(len(content))))
+		Expect(lastTotal).To(Equal(int64(len(content))))
+		data, err := os.ReadFile(filePath)
+		Expect(err).NotTo(HaveOccurred())
+		Expect(string(data)).To(Equal(content))
+	})
+
+	It("should report download progress while streaming chunks", func() {
+		response := &gorest.Response{Response: &http.Response{
+			StatusCode:    200,
+			ContentLength: -1,
+			Body:          io.NopCloser(strings.NewReader("abcdef")),
+		}}
+		var reports [][2]int64
+		err := response.WithDownloadProgress(func(received, total int64) {
+			reports = append(reports, [2]int64{received, total})
+		}).StreamChunks(func([]byte) {}, 4)
+		Expect(err).NotTo(HaveOccurred())
+		Expect(reports).To(Equal([][2]int64{{4, -1}, {6, -1}}))
+	})
+})
@@ -0,0 +1,209 @@
+package gorest
+
+import (
+	"bufio"
+	"fmt"
+	"io"
+	"net/http"
+	"slices"
+	"strconv"
+	"strings"
+	"sync"
+)
+
+// DefaultLatencyBuckets are the upper bounds, in seconds, of the request duration histogram buckets.
+var DefaultLatencyBuckets = []float64{0.005, 0.01, 0.025, 0.05, 0.1, 0.25, 0.5, 1, 2.5, 5, 10}
+
+// PrometheusRecorder is a MetricsRecorder that serves its metrics in the Prometheus text exposition format.
+// It implements http.Handler, so it can be mounted on a metrics endpoint:
+//
+//	metrics := gorest.NewPrometheusRecorder(nil)
+//	client := gorest.NewClient(gorest.WithMiddlewares(gorest.MetricsMiddleware(metrics)))
+//	http.Handle("/metrics", metrics)
+//
+// The following metrics are exposed, labeled by host, method, route and, except for the gauge, status_class:
+// gorest_client_requests_total, gorest_client_request_duration_seconds (histogram),
+// gorest_client_requests_in_flight, gorest_client_request_bytes_total, gorest_client_response_bytes_total
+// and gorest_client_retries_total.
+type PrometheusRecorder struct {
+	buckets []float64
+
+	mu       sync.Mutex
+	series   map[MetricLabels]*requestSeries
+	inFlight map[MetricLabels]int64
+}
+
+// requestSeries holds the metrics of finished requests with the same labels.
+type requestSeries struct {
+	count         uint64
+	bucketCounts  []uint64
+	durationSum   float64
+	requestBytes  int64
+	responseBytes int64
+	retries       int64
+}
+
+// NewPrometheusRecorder returns a PrometheusRecorder using the given histogram bucket upper bounds in seconds.
+// If buckets is empty, DefaultLatencyBuckets is used.
+func NewPrometheusRecorder(buckets []float64) *PrometheusRecorder {
+	if len(buckets) == 0 {
+		buckets = DefaultLatencyBuckets
+	}
+	buckets = slices.Clone(buckets)
+	slices.Sort(buckets)
+	return &PrometheusRecorder{
+		buckets:  buckets,
+		series:   make(map[MetricLabels]*requestSeries),
+		inFlight: make(map[MetricLabels]int64),
+	}
+}
+
+// InFlight implements MetricsRecorder.
+func (p *PrometheusRecorder) InFlight(labels MetricLabels, delta int) {
+	labels.StatusClass = ""
+	p.mu.Lock()
+	defer p.mu.Unlock()
+	p.inFlight[labels] += int64(delta)
+}
+
+// Observe implements MetricsRecorder.
+func (p *PrometheusRecorder) Observe(labels MetricLabels, metrics RequestMetrics) {
+	p.mu.Lock()
+	defer p.mu.Unlock()
+	s, ok := p.series[labels]
+	if !ok {
+		s = &requestSeries{bucketCounts: make([]uint64, len(p.buckets))}
+		p.series[labels] = s
+	}
+	seconds := metrics.Duration.Seconds()
+	s.count++
+	s.durationSum += seconds
+	for i, bound := range p.buckets {
+		if seconds <= bound {
+			s.bucketCounts[i]++
+		}
+	}
+	s.requestBytes += metrics.RequestBytes
+	s.responseBytes += metrics.ResponseBytes
+	s.retries += int64(metrics.Retries)
+}
+
+// ServeHTTP writes the metrics in the Prometheus text exposition format.
+func (p *PrometheusRecorder) ServeHTTP(w http.ResponseWriter, _ *http.Request) {
+	w.Header().Set("Content-Type", "text/plain; version=0.0.4; charset=utf-8")
+	_, _ = p.WriteTo(w)
+}
+
+// WriteTo writes the metrics in the Prometheus text exposition format to w.
+func (p *PrometheusRecorder) WriteTo(w io.Writer) (int64, error) {
+	p.mu.Lock()
+	defer p.mu.Unlock()
+
+	cw := &countingWriter{w: bufio.NewWriter(w)}
+	labels := make([]MetricLabels, 0, len(p.series))
+	for l := range p.series {
+		labels = append(labels, l)
+	}
+	slices.SortFunc(labels, compareMetricLabels)
+
+	writeHeader := func(name, kind, help string) {
+		fmt.Fprintf(cw, "# HELP %s %s\n# TYPE %s %s\n", name, help, name, kind)
+	}
+	writeCounter := func(name, help string, value func(*requestSeries) string) {
+		writeHeader(name, "counter", help)
+		for _, l := range labels {
+			fmt.Fprintf(cw, "%s{%s} %s\n", name, formatMetricLabels(l, ""), value(p.series[l]))
+		}
+	}
+
+	writeCounter("gorest_client_requests_total", "Total number of requests sent.", func(s *requestSeries) string {
+		return strconv.FormatUint(s.count, 10)
+	})
+
+	name := "gorest_client_request_duration_seconds"
+	writeHeader(name, "histogram", "Time until the response headers were received.")
+	for _, l := range labels {
+		s := p.series[l]
+		for i, bound := range p.buckets {
+			fmt.Fprintf(cw, "%s_bucket{%s} %d\n", name, formatMetricLabels(l, formatFloat(bound)), s.bucketCounts[i])
+		}
+		fmt.Fprintf(cw, "%s_bucket{%s} %d\n", name, formatMetricLabels(l, "+Inf"), s.count)
+		fmt.Fprintf(cw, "%s_sum{%s} %s\n", name, formatMetricLabels(l, ""), formatFloat(s.durationSum))
+		fmt.Fprintf(cw, "%s_count{%s} %d\n", name, formatMetricLabels(l, ""), s.count)
+	}
+
+	name = "gorest_client_requests_in_flight"
+	writeHeader(name, "gauge", "Number of requests whose response body has not been closed yet.")
+	inFlight := make([]MetricLabels, 0, len(p.inFlight))
+	for l := range p.inFlight {
+		inFlight = append(inFlight, l)
+	}
+	slices.SortFunc(inFlight, compareMetricLabels)
+	for _, l := range inFlight {
+		fmt.Fprintf(cw, "%s{%s} %d\n", name, formatMetricLabels(l, ""), p.inFlight[l])
+	}
+
+	writeCounter("gorest_client_request_bytes_total", "Total size of the request bodies sent.", func(s *requestSeries) string {
+		return strconv.FormatInt(s.requestBytes, 10)
+	})
+	writeCounter("gorest_client_response_bytes_total", "Total size of the response bodies read.", func(s *requestSeries) string {
+		return strconv.FormatInt(s.responseBytes, 10)
+	})
+	writeCounter("gorest_client_retries_total", "Total number of retry attempts.", func(s *requestSeries) string {
+		return strconv.FormatInt(s.retries, 10)
+	})
+
+	if cw.err == nil {
+		cw.err = cw.w.Flush()
+	}
+	return cw.n, cw.err
+}
+
+func compareMetricLabels(a, b MetricLabels) int {
+	for _, c := range [][2]string{{a.Host, b.Host}, {a.Method, b.Method}, {a.Route, b.Route}, {a.StatusClass, b.StatusClass}} {
+		if n := strings.Compare(c[0], c[1]); n != 0 {
+			return n
+		}
+	}
+	return 0
+}
+
+// formatMetricLabels formats the labels of a sample, including le for histogram buckets if not empty.
+func formatMetricLabels(l MetricLabels, le string) string {
+	var b strings.Builder
+	fmt.Fprintf(&b, `host="%s",method="%s",route="%s"`, escapeLabelValue(l.Host), escapeLabelValue(l.Method), escapeLabelValue(l.Route))
+	if l.StatusClass != "" {
+		fmt.Fprintf(&b, `,status_class="%s"`, escapeLabelValue(l.StatusClass))
+	}
+	if le != "" {
+		fmt.Fprintf(&b, `,le="%s"`, le)
+	}
+	return b.String()
+}
+
+var labelValueEscaper = strings.NewReplacer(`\`, `\\`, `"`, `\"`, "\n", `\n`)
+
+func escapeLabelValue(s string) string {
+	return labelValueEscaper.Replace(s)
+}
+
+func formatFloat(f float64) string {
+	return strconv.FormatFloat(f, 'g', -1, 64)
+}
+
+// countingWriter counts the bytes written to w and keeps the first error.
+type countingWriter struct {
+	w   *bufio.Writer
+	n   int64
+	err error
+}
+
+func (c *countingWriter) Write(p []byte) (int, error) {
+	if c.err != nil {
+		return 0, c.err
+	}
+	n, err := c.w.Write(p)
+	c.n += int64(n)
+	c.err = err
+	return n, err
+}
@@ -0,0 +1,79 @@
+package gorest_test
+
+import (
+	"io"
+	"net/http/httptest"
+	"strings"
+	"time"
+
+	. "github.com/onsi/ginkgo/v2"
+	. "github.com/onsi/gomega"
+
+	"gorest/gorest"
+)
+
+var _ = Describe("PrometheusRecorder", func() {
+	It("should write the text exposition format", func() {
+		recorder := gorest.NewPrometheusRecorder([]float64{0.5, 0.1})
+		ok := gorest.MetricLabels{Host: "api.example.com", Method: "GET", StatusClass: "2xx", Route: "/users/{id}"}
+		failed := gorest.MetricLabels{Host: "api.example.com", Method: "GET", StatusClass: "5xx", Route: `/a"b`}
+		recorder.InFlight(ok, 1)
+		recorder.InFlight(ok, 1)
+		recorder.InFlight(ok, -1)
+		recorder.Observe(ok, gorest.RequestMetrics{Duration: 50 * time.Millisecond, RequestBytes: 10, ResponseBytes: 100})
+		recorder.Observe(ok, gorest.RequestMetrics{Duration: 200 * time.Millisecond, ResponseBytes: 20, Retries: 1})
+		recorder.Observe(failed, gorest.RequestMetrics{Duration: time.Second, Retries: 2})
+
+		var b strings.Builder
+		n, err := recorder.WriteTo(&b)
+		Expect(err).NotTo(HaveOccurred())
+		Expect(n).To(Equal(int64(b.Len())))
+		Expect(b.String()).To(Equal(`# HELP gorest_client_requests_total Total number of requests sent.
+# TYPE gorest_client_requests_total counter
+gorest_client_requests_total{host="api.example.com",method="GET",route="/a\"b",status_class="5xx"} 1
+gorest_client_requests_total{host="api.example.com",method="GET",route="/users/{id}",status_class="2xx"} 2
+# HELP gorest_client_request_duration_seconds Time until the response headers were received.
+# TYPE gorest_client_request_duration_seconds histogram
+gorest_client_request_duration_seconds_bucket{host="api.example.com",method="GET",route="/a\"b",status_class="5xx",le="0.1"} 0
+gorest_client_request_duration_seconds_bucket{host="api.example.com",method="GET",route="/a\"b",status_class="5xx",le="0.5"} 0
+gorest_client_request_duration_seconds_bucket{host="api.example.com",method="GET",route="/a\"b",status_class="5xx",le="+Inf"} 1
+gorest_client_request_duration_seconds_sum{host="api.example.com",method="GET",route="/a\"b",status_class="5xx"} 1
+gorest_client_request_duration_seconds_count{host="api.example.com",method="GET",route="/a\"b",status_class="5xx"} 1
+gorest_client_request_duration_seconds_bucket{host="api.example.com",method="GET",route="/users/{id}",status_class="2xx",le="0.1"} 1
+gorest_client_request_duration_seconds_bucket{host="api.example.com",method="GET",route="/users/{id}",status_class="2xx",le="0.5"} 2
+gorest_client_request_duration_seconds_bucket{host="api.example.com",method="GET",route="/users/{id}",status_class="2xx",le="+Inf"} 2
+gorest_client_request_duration_seconds_sum{host="api.example.com",method="GET",route="/users/{id}",status_class="2xx"} 0.25
+gorest_client_request_duration_seconds_count{host="api.example.com",method="GET",route="/users/{id}",status_class="2xx"} 2
+# HELP gorest_client_requests_in_flight Number of requests whose response body has not been closed yet.
+# TYPE gorest_client_requests_in_flight gauge
+gorest_client_requests_in_flight{host="api.example.com",method="GET",route="/users/{id}"} 1
+# HELP gorest_client_request_bytes_total Total size of the request bodies sent.
+# TYPE gorest_client_request_bytes_total counter
+gorest_client_request_bytes_total{host="api.example.com",method="GET",route="/a\"b",status_class="5xx"} 0
+gorest_client_request_bytes_total{host="api.example.com",method="GET",route="/users/{id}",status_class="2xx"} 10
+# HELP gorest_client_response_bytes_total Total size of the response bodies read.
+# TYPE gorest_client_response_bytes_total counter
+gorest_client_response_bytes_total{host="api.example.com",method="GET",route="/a\"b",status_class="5xx"} 0
+gorest_client_response_bytes_total{host="api.example.com",method="GET",route="/users/{id}",status_class="2xx"} 120
+# HELP gorest_client_retries_total Total number of retry attempts.
+# TYPE gorest_client_retries_total counter
+gorest_client_retries_total{host="api.example.com",method="GET",route="/a\"b",status_class="5xx"} 2
+gorest_client_retries_total{host="api.example.com",method="GET",route="/users/{id}",status_class="2xx"} 1
+`))
+	})
+
+	It("should serve the metrics over HTTP", func() {
+		recorder := gorest.NewPrometheusRecorder(nil)
+		recorder.Observe(gorest.MetricLabels{Host: "h", Method: "GET", StatusClass: "2xx"}, gorest.RequestMetrics{Duration: time.Millisecond})
+		server := httptest.NewServer(recorder)
+		defer server.Close()
+
+		resp, err := server.Client().Get(server.URL)
+		Expect(err).NotTo(HaveOccurred())
+		defer resp.Body.Close()
+		Expect(resp.Header.Get("Content-Type")).To(Equal("text/plain; version=0.0.4; charset=utf-8"))
+		body, _ := io.ReadAll(resp.Body)
+		Expect(string(body)).To(ContainSubstring(`gorest_client_request_duration_seconds_bucket{host="h",method="GET",route="",status_class="2xx",le="0.005"} 1`))
+		Expect(string(body)).To(ContainSubstring(`gorest_client_requests_total{host="h",method="GET",route="",status_class="2xx"} 1`))
+	})
+})
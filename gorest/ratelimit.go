@@ -0,0 +1,194 @@
+package gorest
+
+import (
+	"math"
+	"net/http"
+	"strconv"
+	"strings"
+	"sync"
+	"time"
+)
+
+// RateLimitConfig configures the RateLimitMiddleware.
+type RateLimitConfig struct {
+	// Rate is the number of requests per second allowed for each key. Zero means no client-side limit,
+	// which is useful when only the server hints should be followed.
+	Rate float64
+	// Burst is the number of requests that may be sent at once. Defaults to Rate rounded up, and at least 1.
+	Burst int
+	// KeyFunc selects the bucket for a request. Defaults to the request host.
+	KeyFunc func(req *http.Request) string
+	// AdaptToHeaders makes the limiter follow the rate limit hints sent by the server: Retry-After on 429
+	// and 503 responses, X-RateLimit-Remaining/X-RateLimit-Reset, RateLimit-Remaining/RateLimit-Reset
+	// and the combined RateLimit header.
+	AdaptToHeaders bool
+}
+
+// RateLimitMiddleware returns a middleware that limits the rate of requests with a token bucket per key,
+// waiting for a token before calling next. If the request context is done while waiting, the context error
+// is returned without sending the request.
+func RateLimitMiddleware(config *RateLimitConfig) Middleware {
+	cfg := RateLimitConfig{}
+	if config != nil {
+		cfg = *config
+	}
+	if cfg.Burst <= 0 {
+		cfg.Burst = max(1, int(math.Ceil(cfg.Rate)))
+	}
+	if cfg.KeyFunc == nil {
+		cfg.KeyFunc = func(req *http.Request) string { return req.URL.Host }
+	}
+
+	var mu sync.Mutex
+	buckets := make(map[string]*tokenBucket)
+	get := func(key string) *tokenBucket {
+		mu.Lock()
+		defer mu.Unlock()
+		b, ok := buckets[key]
+		if !ok {
+			b = &tokenBucket{rate: cfg.Rate, burst: float64(cfg.Burst), tokens: float64(cfg.Burst), last: time.Now()}
+			buckets[key] = b
+		}
+		return b
+	}
+
+	return func(next RoundTripFunc) RoundTripFunc {
+		return func(req *http.Request) (*http.Response, error) {
+			b := get(cfg.KeyFunc(req))
+			if err := sleepContext(req.Context(), b.reserve(time.Now())); err != nil {
+				b.cancel()
+				return nil, err
+			}
+			resp, err := next(req)
+			if cfg.AdaptToHeaders && resp != nil {
+				b.adapt(resp, time.Now())
+			}
+			return resp, err
+		}
+	}
+}
+
+// tokenBucket is a token bucket that lets callers reserve tokens ahead of time.
+// The token count goes negative when callers are waiting for tokens.
+type tokenBucket struct {
+	mu     sync.Mutex
+	rate   float64
+	burst  float64
+	tokens float64
+	last   time.Time
+	// blockedUntil is set from server hints; no request is sent before it.
+	blockedUntil time.Time
+}
+
+// reserve takes a token and returns how long the caller must wait before using it.
+func (b *tokenBucket) reserve(now time.Time) time.Duration {
+	b.mu.Lock()
+	defer b.mu.Unlock()
+
+	var wait time.Duration
+	if b.rate > 0 {
+		b.refill(now)
+		b.tokens--
+		if b.tokens < 0 {
+			wait = time.Duration(-b.tokens / b.rate * float64(time.Second))
+		}
+	}
+	if blocked := b.blockedUntil.Sub(now); blocked > wait {
+		wait = blocked
+	}
+	return wait
+}
+
+// cancel returns a token reserved by a caller that gave up waiting.
+func (b *tokenBucket) cancel() {
+	b.mu.Lock()
+	defer b.mu.Unlock()
+	if b.rate > 0 {
+		b.tokens = min(b.tokens+1, b.burst)
+	}
+}
+
+func (b *tokenBucket) refill(now time.Time) {
+	if elapsed := now.Sub(b.last); elapsed > 0 {
+		b.tokens = min(b.burst, b.tokens+elapsed.Seconds()*b.rate)
+		b.last = now
+	}
+}
+
+// adapt updates the bucket from the rate limit headers of resp.
+func (b *tokenBucket) adapt(resp *http.Response, now time.Time) {
+	b.mu.Lock()
+	defer b.mu.Unlock()
+
+	if resp.StatusCode == http.StatusTooManyRequests || resp.StatusCode == http.StatusServiceUnavailable {
+		if d, err := ParseRetryAfter(resp.Header.Get("Retry-After"), now); err == nil {
+			b.block(now.Add(d))
+		}
+	}
+
+	remaining, reset, ok := parseRateLimitHeaders(resp.Header, now)
+	if !ok {
+		return
+	}
+	if remaining <= 0 {
+		if reset > 0 {
+			b.block(now.Add(reset))
+		}
+		return
+	}
+	if b.rate > 0 {
+		b.refill(now)
+		b.tokens = min(b.tokens, float64(remaining))
+	}
+}
+
+func (b *tokenBucket) block(until time.Time) {
+	if until.After(b.blockedUntil) {
+		b.blockedUntil = until
+	}
+}
+
+// parseRateLimitHeaders returns the remaining quota and the time until it resets from the combined RateLimit
+// header, the RateLimit-Remaining/RateLimit-Reset headers or the X-RateLimit-Remaining/X-RateLimit-Reset headers.
+// reset is zero when unknown.
+func parseRateLimitHeaders(h http.Header, now time.Time) (remaining int, reset time.Duration, ok bool) {
+	if v := h.Get("RateLimit"); v != "" {
+		// Both "limit=100, remaining=50, reset=30" and the structured `"default";r=50;t=30` forms.
+		var haveRemaining bool
+		for _, field := range strings.FieldsFunc(v, func(r rune) bool { return r == ',' || r == ';' }) {
+			name, value, found := strings.Cut(strings.TrimSpace(field), "=")
+			if !found {
+				continue
+			}
+			n, err := strconv.Atoi(strings.TrimSpace(value))
+			if err != nil {
+				continue
+			}
+			switch name {
+			case "remaining", "r":
+				remaining, haveRemaining = n, true
+			case "reset", "t":
+				reset = time.Duration(n) * time.Second
+			}
+		}
+		if haveRemaining {
+			return remaining, reset, true
+		}
+	}
+
+	for _, prefix := range []string{"RateLimit-", "X-RateLimit-"} {
+		n, err := strconv.Atoi(h.Get(prefix + "Remaining"))
+		if err != nil {
+			continue
+		}
+		if v, err := strconv.ParseInt(h.Get(prefix+"Reset"), 10, 64); err == nil {
+			reset = time.Duration(v) * time.Second
+			// X-RateLimit-Reset is often a Unix timestamp rather than a number of seconds.
+			if v > 1e9 {
+				reset = max(0, time.Unix(v, 0).Sub(now))
+			}
+		}
+		return n, reset, true
+	}
+	return 0, 0, false
+}
@@ -0,0 +1,124 @@
+package gorest_test
+
+import (
+	"context"
+	"errors"
+	"io"
+	"net/http"
+	"strconv"
+	"strings"
+	"sync/atomic"
+	"time"
+
+	. "github.com/onsi/ginkgo/v2"
+	. "github.com/onsi/gomega"
+
+	"gorest/gorest"
+)
+
+var _ = Describe("RateLimitMiddleware", func() {
+	var (
+		calls  int32
+		header http.Header
+		next   gorest.RoundTripFunc
+	)
+
+	BeforeEach(func() {
+		atomic.StoreInt32(&calls, 0)
+		header = http.Header{}
+		next = func(req *http.Request) (*http.Response, error) {
+			atomic.AddInt32(&calls, 1)
+			return &http.Response{
+				StatusCode: 200,
+				Header:     header.Clone(),
+				Body:       io.NopCloser(strings.NewReader("")),
+			}, nil
+		}
+	})
+
+	send := func(ctx context.Context, rt gorest.RoundTripFunc, url string) error {
+		req, err := http.NewRequestWithContext(ctx, "GET", url, nil)
+		Expect(err).NotTo(HaveOccurred())
+		_, err = rt(req)
+		return err
+	}
+
+	It("should space requests according to the rate", func() {
+		rt := gorest.RateLimitMiddleware(&gorest.RateLimitConfig{Rate: 20, Burst: 1})(next)
+		start := time.Now()
+		for i := 0; i < 3; i++ {
+			Expect(send(context.Background(), rt, "http://example.com")).To(Succeed())
+		}
+		Expect(time.Since(start)).To(BeNumerically(">=", 90*time.Millisecond))
+	})
+
+	It("should allow a burst without waiting", func() {
+		rt := gorest.RateLimitMiddleware(&gorest.RateLimitConfig{Rate: 1, Burst: 5})(next)
+		start := time.Now()
+		for i := 0; i < 5; i++ {
+			Expect(send(context.Background(), rt, "http://example.com")).To(Succeed())
+		}
+		Expect(time.Since(start)).To(BeNumerically("<", 50*time.Millisecond))
+	})
+
+	It("should keep separate buckets per host", func() {
+		rt := gorest.RateLimitMiddleware(&gorest.RateLimitConfig{Rate: 1, Burst: 1})(next)
+		start := time.Now()
+		Expect(send(context.Background(), rt, "http://a.example.com")).To(Succeed())
+		Expect(send(context.Background(), rt, "http://b.example.com")).To(Succeed())
+		Expect(time.Since(start)).To(BeNumerically("<", 50*time.Millisecond))
+	})
+
+	It("should stop waiting when the context is done", func() {
+		rt := gorest.RateLimitMiddleware(&gorest.RateLimitConfig{Rate: 1, Burst: 1})(next)
+		Expect(send(context.Background(), rt, "http://example.com")).To(Succeed())
+
+		ctx, cancel := context.WithTimeout(context.Background(), 20*time.Millisecond)
+		defer cancel()
+		err := send(ctx, rt, "http://example.com")
+		Expect(errors.Is(err, context.DeadlineExceeded)).To(BeTrue())
+		Expect(atomic.LoadInt32(&calls)).To(Equal(int32(1)))
+	})
+
+	Context("with AdaptToHeaders", func() {
+		expectBlocked := func(rt gorest.RoundTripFunc) {
+			Expect(send(context.Background(), rt, "http://example.com")).To(Succeed())
+			ctx, cancel := context.WithTimeout(context.Background(), 50*time.Millisecond)
+			defer cancel()
+			Expect(errors.Is(send(ctx, rt, "http://example.com"), context.DeadlineExceeded)).To(BeTrue())
+			Expect(atomic.LoadInt32(&calls)).To(Equal(int32(1)))
+		}
+
+		It("should wait for X-RateLimit-Reset when the quota is exhausted", func() {
+			header.Set("X-RateLimit-Remaining", "0")
+			header.Set("X-RateLimit-Reset", strconv.FormatInt(time.Now().Add(10*time.Second).Unix(), 10))
+			expectBlocked(gorest.RateLimitMiddleware(&gorest.RateLimitConfig{AdaptToHeaders: true})(next))
+		})
+
+		It("should follow the RateLimit-Remaining and RateLimit-Reset headers", func() {
+			header.Set("RateLimit-Remaining", "0")
+			header.Set("RateLimit-Reset", "10")
+			expectBlocked(gorest.RateLimitMiddleware(&gorest.RateLimitConfig{AdaptToHeaders: true})(next))
+		})
+
+		It("should follow the structured RateLimit header", func() {
+			header.Set("RateLimit", `"default";r=0;t=10`)
+			expectBlocked(gorest.RateLimitMiddleware(&gorest.RateLimitConfig{AdaptToHeaders: true})(next))
+		})
+
+		It("should resume once the reset time has passed", func() {
+			header.Set("RateLimit", "limit=10, remaining=0, reset=0")
+			rt := gorest.RateLimitMiddleware(&gorest.RateLimitConfig{AdaptToHeaders: true})(next)
+			Expect(send(context.Background(), rt, "http://example.com")).To(Succeed())
+			Expect(send(context.Background(), rt, "http://example.com")).To(Succeed())
+		})
+
+		It("should ignore the headers when disabled", func() {
+			header.Set("RateLimit-Remaining", "0")
+			header.Set("RateLimit-Reset", "10")
+			rt := gorest.RateLimitMiddleware(&gorest.RateLimitConfig{})(next)
+			Expect(send(context.Background(), rt, "http://example.com")).To(Succeed())
+			Expect(send(context.Background(), rt, "http://example.com")).To(Succeed())
+		})
+	})
+})
@@ -2,6 +2,7 @@ package gorest
 
 import (
 	"bytes"
+	"context"
 	"encoding/json"
 	"errors"
 	"fmt"
@@ -11,15 +12,31 @@ import (
 	"net/url"
 	"os"
 	"path/filepath"
+	"regexp"
+	"strings"
 )
 
+// pathParamPattern matches {name} placeholders in request URLs.
+var pathParamPattern = regexp.MustCompile(`\{([^{}/]+)\}`)
+
 // Request represents an API request with configurable headers, query parameters, and body.
 type Request struct {
-	method      string
-	url         string
+	method  string
+	url     string
+	baseURL string
+	// Values for {name} placeholders in the URL.
+	pathParams  map[string]string
 	headers     map[string]string
 	queryParams url.Values
 	body        io.Reader
+	// A value encoded with the codec for bodyContentType when the request is built.
+	bodyValue       interface{}
+	bodyContentType string
+	// Parts streamed through a pipe when the request is built.
+	multipartParts    []MultipartPart
+	multipartBoundary string
+	// Reports upload progress of the body, if set.
+	uploadProgress ProgressFunc
 	// Indicates whether the body was built as multipart.
 	isMultipart bool
 	// Holds any error encountered during body building.
@@ -36,6 +53,62 @@ func NewRequest(method, urlStr string) *Request {
 	}
 }
 
+// clone returns a copy of the Request that can be modified and sent independently.
+// In-memory bodies are copied so each clone sends the full body; other readers are shared.
+func (r *Request) clone() *Request {
+	c := *r
+	c.headers = make(map[string]string, len(r.headers))
+	for k, v := range r.headers {
+		c.headers[k] = v
+	}
+	c.queryParams = make(url.Values, len(r.queryParams))
+	for k, v := range r.queryParams {
+		c.queryParams[k] = append([]string(nil), v...)
+	}
+	if r.pathParams != nil {
+		c.pathParams = make(map[string]string, len(r.pathParams))
+		for k, v := range r.pathParams {
+			c.pathParams[k] = v
+		}
+	}
+	switch body := r.body.(type) {
+	case *bytes.Reader:
+		cp := *body
+		c.body = &cp
+	case *bytes.Buffer:
+		c.body = bytes.NewReader(body.Bytes())
+	case *strings.Reader:
+		cp := *body
+		c.body = &cp
+	}
+	return &c
+}
+
+// WithBaseURL sets the base URL that relative request URLs are resolved against.
+// It takes precedence over a base URL configured on the Client with WithBaseURL.
+func (r *Request) WithBaseURL(baseURL string) *Request {
+	r.baseURL = baseURL
+	return r
+}
+
+// WithPathParam sets the value substituted for the {key} placeholder in the request URL.
+// The value is escaped as a single path segment.
+func (r *Request) WithPathParam(key, value string) *Request {
+	if r.pathParams == nil {
+		r.pathParams = make(map[string]string)
+	}
+	r.pathParams[key] = value
+	return r
+}
+
+// WithPathParams sets multiple path parameters on the Request.
+func (r *Request) WithPathParams(params map[string]string) *Request {
+	for k, v := range params {
+		r.WithPathParam(k, v)
+	}
+	return r
+}
+
 // WithHeader adds a single header to the Request.
 func (r *Request) WithHeader(key, value string) *Request {
 	r.headers[key] = value
@@ -75,21 +148,54 @@ func (r *Request) WithJSONBody(data interface{}) *Request {
 	return r
 }
 
+// WithXMLBody sets the request body to the XML representation of the provided data
+// and sets the Content-Type header to application/xml.
+func (r *Request) WithXMLBody(data interface{}) *Request {
+	b, err := XMLCodec{}.Marshal(data)
+	if err != nil {
+		r.buildErr = err
+		return r
+	}
+	r.body = bytes.NewReader(b)
+	r.WithHeader("Content-Type", XMLCodec{}.ContentType())
+	return r
+}
+
+// WithFormBody sets the request body to the URL-encoded form values
+// and sets the Content-Type header to application/x-www-form-urlencoded.
+func (r *Request) WithFormBody(values url.Values) *Request {
+	r.body = bytes.NewReader([]byte(values.Encode()))
+	r.WithHeader("Content-Type", FormCodec{}.ContentType())
+	return r
+}
+
+// WithEncodedBody sets the request body to data, encoded when the request is built with the codec
+// registered for contentType (see WithCodecs), and sets the Content-Type header to contentType.
+func (r *Request) WithEncodedBody(contentType string, data interface{}) *Request {
+	r.bodyValue = data
+	r.bodyContentType = contentType
+	r.WithHeader("Content-Type", contentType)
+	return r
+}
+
 // WithMultipartForm constructs a multipart/form-data body from formFields and fileFields.
+// Fields are written in sorted key order, form fields first. The whole body is buffered in memory;
+// use WithMultipartStream for large files.
 // If any error occurs (e.g. file not found), it is stored in the Request.
 func (r *Request) WithMultipartForm(formFields map[string]string, fileFields map[string]string) *Request {
 	var b bytes.Buffer
 	writer := multipart.NewWriter(&b)
 
-	// Add form fields.
-	for key, val := range formFields {
-		if err := writer.WriteField(key, val); err != nil {
+	// Add form fields in sorted order so the body is deterministic.
+	for _, key := range sortedKeys(formFields) {
+		if err := writer.WriteField(key, formFields[key]); err != nil {
 			r.buildErr = err
 			return r
 		}
 	}
 
-	for field, filePath := range fileFields {
+	for _, field := range sortedKeys(fileFields) {
+		filePath := fileFields[field]
 		if err := func() (retErr error) {
 			file, err := os.Open(filePath)
 			if err != nil {
@@ -129,13 +235,24 @@ func (r *Request) WithMultipartForm(formFields map[string]string, fileFields map
 // BuildHTTPRequest constructs an *http.Request from the Request.
 // It returns an error if any issue occurred during building (e.g. invalid URL or previous build error).
 func (r *Request) BuildHTTPRequest() (*http.Request, error) {
+	return r.buildHTTPRequest("", defaultCodecs)
+}
+
+// buildHTTPRequest constructs an *http.Request, resolving relative URLs against defaultBaseURL
+// unless the Request has its own base URL, and encoding deferred bodies with codecs.
+func (r *Request) buildHTTPRequest(defaultBaseURL string, codecs codecRegistry) (*http.Request, error) {
 	if r.buildErr != nil {
 		return nil, r.buildErr
 	}
-	if r.url == "" {
-		return nil, errors.New("request URL is empty")
+	baseURL := r.baseURL
+	if baseURL == "" {
+		baseURL = defaultBaseURL
+	}
+	rawURL, err := resolveURL(baseURL, r.url, r.pathParams)
+	if err != nil {
+		return nil, err
 	}
-	parsedURL, err := url.Parse(r.url)
+	parsedURL, err := url.Parse(rawURL)
 	if err != nil {
 		return nil, fmt.Errorf("invalid URL: %w", err)
 	}
@@ -147,19 +264,104 @@ func (r *Request) BuildHTTPRequest() (*http.Request, error) {
 	}
 	parsedURL.RawQuery = q.Encode()
 
-	httpReq, err := http.NewRequest(r.method, parsedURL.String(), r.body)
+	body := r.body
+	if r.multipartParts != nil {
+		body = streamMultipart(r.multipartBoundary, r.multipartParts)
+	}
+	if r.bodyValue != nil {
+		codec, err := codecs.lookup(r.bodyContentType)
+		if err != nil {
+			return nil, err
+		}
+		b, err := codec.Marshal(r.bodyValue)
+		if err != nil {
+			return nil, err
+		}
+		body = bytes.NewReader(b)
+	}
+
+	httpReq, err := http.NewRequest(r.method, parsedURL.String(), body)
 	if err != nil {
 		return nil, err
 	}
 	for key, value := range r.headers {
 		httpReq.Header.Set(key, value)
 	}
+	if r.uploadProgress != nil {
+		wrapUploadProgress(httpReq, r.uploadProgress)
+	}
 	return httpReq, nil
 }
 
+// urlTemplateKey is the context key under which the URL template of a request is stored.
+type urlTemplateKey struct{}
+
+// withURLTemplate returns a context carrying the URL of req if it has path parameters, or an empty template
+// otherwise, so that requests sent while handling another one do not inherit its template.
+func withURLTemplate(ctx context.Context, req *Request) context.Context {
+	template := ""
+	if len(req.pathParams) > 0 {
+		template = req.url
+	}
+	return context.WithValue(ctx, urlTemplateKey{}, template)
+}
+
+// URLTemplate returns the URL of a request sent by a Client before its path parameters were substituted,
+// as in "/users/{id}", or "" if the request was built without path parameters.
+// Unlike the URL itself, it is suited for naming spans and labeling metrics.
+func URLTemplate(req *http.Request) string {
+	template, _ := req.Context().Value(urlTemplateKey{}).(string)
+	return template
+}
+
+// resolveURL substitutes path parameters, if any, into the path of urlStr and, if it is relative, joins it to baseURL.
+// Joining keeps the path of baseURL, so "https://api/v1" and "/users" resolve to "https://api/v1/users".
+func resolveURL(baseURL, urlStr string, pathParams map[string]string) (string, error) {
+	if len(pathParams) > 0 {
+		// Placeholders are only substituted in the path, so braces in the query or fragment are kept as is.
+		path, rest := urlStr, ""
+		if i := strings.IndexAny(urlStr, "?#"); i >= 0 {
+			path, rest = urlStr[:i], urlStr[i:]
+		}
+		var missing []string
+		path = pathParamPattern.ReplaceAllStringFunc(path, func(placeholder string) string {
+			name := placeholder[1 : len(placeholder)-1]
+			value, ok := pathParams[name]
+			if !ok {
+				missing = append(missing, name)
+				return placeholder
+			}
+			return url.PathEscape(value)
+		})
+		if len(missing) > 0 {
+			return "", fmt.Errorf("missing path parameters: %s", strings.Join(missing, ", "))
+		}
+		urlStr = path + rest
+	}
+
+	if baseURL == "" {
+		if urlStr == "" {
+			return "", errors.New("request URL is empty")
+		}
+		return urlStr, nil
+	}
+	if u, err := url.Parse(urlStr); err == nil && u.IsAbs() {
+		return urlStr, nil
+	}
+	if urlStr == "" {
+		return baseURL, nil
+	}
+	if strings.HasPrefix(urlStr, "?") {
+		return strings.TrimRight(baseURL, "/") + urlStr, nil
+	}
+	return strings.TrimRight(baseURL, "/") + "/" + strings.TrimLeft(urlStr, "/"), nil
+}
+
 // Response wraps a http.Response to provide helper methods.
 type Response struct {
 	*http.Response
+	// codecs used by Decode, inherited from the Client.
+	codecs codecRegistry
 }
 
 // Close closes the response body.
@@ -178,6 +380,29 @@ func (r *Response) JSON(v interface{}) (err error) {
 	return json.NewDecoder(r.Body).Decode(v)
 }
 
+// Decode decodes the response body into v with the codec matching the response Content-Type.
+// Codecs registered on the Client with WithCodecs take precedence over the built-in JSON, XML and form codecs.
+// A response without a Content-Type is decoded as JSON. It automatically closes the response body.
+func (r *Response) Decode(v interface{}) error {
+	body, err := r.Bytes()
+	if err != nil {
+		return err
+	}
+	contentType := r.Header.Get("Content-Type")
+	if contentType == "" {
+		contentType = JSONCodec{}.ContentType()
+	}
+	codecs := r.codecs
+	if codecs == nil {
+		codecs = defaultCodecs
+	}
+	codec, err := codecs.lookup(contentType)
+	if err != nil {
+		return err
+	}
+	return codec.Unmarshal(body, v)
+}
+
 // Bytes reads the full response body into a byte slice.
 // It automatically closes the response body.
 func (r *Response) Bytes() (body []byte, err error) {
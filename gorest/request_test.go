@@ -71,6 +71,52 @@ var _ = Describe("Request", func() {
 		Expect(err).To(HaveOccurred())
 	})
 
+	It("should substitute and escape path parameters", func() {
+		req := gorest.NewRequest("GET", "http://example.com/users/{id}/orders/{order}").
+			WithPathParam("id", "a b/c").
+			WithPathParams(map[string]string{"order": "42"})
+		httpReq, err := req.BuildHTTPRequest()
+		Expect(err).NotTo(HaveOccurred())
+		Expect(httpReq.URL.EscapedPath()).To(Equal("/users/a%20b%2Fc/orders/42"))
+	})
+
+	It("should return an error for missing path parameters", func() {
+		req := gorest.NewRequest("GET", "http://example.com/users/{id}/orders/{order}").WithPathParam("order", "42")
+		_, err := req.BuildHTTPRequest()
+		Expect(err).To(HaveOccurred())
+		Expect(err.Error()).To(ContainSubstring("missing path parameters: id"))
+	})
+
+	It("should keep braces in the query string", func() {
+		httpReq, err := gorest.NewRequest("GET", `http://example.com/search?filter={"a":1}`).BuildHTTPRequest()
+		Expect(err).NotTo(HaveOccurred())
+		Expect(httpReq.URL.Query().Get("filter")).To(Equal(`{"a":1}`))
+
+		httpReq, err = gorest.NewRequest("GET", `http://example.com/users/{id}?filter={"a":1}`).WithPathParam("id", "7").BuildHTTPRequest()
+		Expect(err).NotTo(HaveOccurred())
+		Expect(httpReq.URL.Path).To(Equal("/users/7"))
+		Expect(httpReq.URL.Query().Get("filter")).To(Equal(`{"a":1}`))
+	})
+
+	It("should resolve relative URLs against the request base URL", func() {
+		req := gorest.NewRequest("GET", "/users/{id}?expand=true").
+			WithBaseURL("http://example.com/api/v1/").
+			WithPathParam("id", "7").
+			WithQueryParam("page", "2")
+		httpReq, err := req.BuildHTTPRequest()
+		Expect(err).NotTo(HaveOccurred())
+		Expect(httpReq.URL.Path).To(Equal("/api/v1/users/7"))
+		Expect(httpReq.URL.Query().Get("expand")).To(Equal("true"))
+		Expect(httpReq.URL.Query().Get("page")).To(Equal("2"))
+	})
+
+	It("should keep absolute URLs when a base URL is set", func() {
+		req := gorest.NewRequest("GET", "http://other.com/x").WithBaseURL("http://example.com/api")
+		httpReq, err := req.BuildHTTPRequest()
+		Expect(err).NotTo(HaveOccurred())
+		Expect(httpReq.URL.String()).To(Equal("http://other.com/x"))
+	})
+
 	Context("Multipart Form", func() {
 		var (
 			tmpFile  *os.File
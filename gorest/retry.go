@@ -0,0 +1,298 @@
+package gorest
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"math"
+	"math/rand/v2"
+	"net/http"
+	"time"
+)
+
+// RetryPolicy decides whether a request should be retried after an attempt.
+// Exactly one of resp and err is non-nil. attempt is the number of the attempt that just finished, starting at 1.
+type RetryPolicy interface {
+	ShouldRetry(req *http.Request, resp *http.Response, err error, attempt int) bool
+}
+
+// RetryPolicyFunc is an adapter to allow the use of ordinary functions as RetryPolicy.
+type RetryPolicyFunc func(req *http.Request, resp *http.Response, err error, attempt int) bool
+
+// ShouldRetry calls f(req, resp, err, attempt).
+func (f RetryPolicyFunc) ShouldRetry(req *http.Request, resp *http.Response, err error, attempt int) bool {
+	return f(req, resp, err, attempt)
+}
+
+// DefaultRetryPolicy retries 429 responses for any request, and transport errors and 5xx responses
+// (except 501) only for idempotent requests. See IsIdempotent. Requests rejected by an open circuit breaker
+// are not retried.
+var DefaultRetryPolicy RetryPolicy = RetryPolicyFunc(defaultShouldRetry)
+
+func defaultShouldRetry(req *http.Request, resp *http.Response, err error, _ int) bool {
+	if req.Context().Err() != nil {
+		return false
+	}
+	if err != nil {
+		return !errors.Is(err, ErrCircuitOpen) && IsIdempotent(req)
+	}
+	switch {
+	case resp.StatusCode == http.StatusTooManyRequests:
+		// The server did not process the request, so it is safe to send it again.
+		return true
+	case resp.StatusCode == http.StatusNotImplemented:
+		return false
+	case resp.StatusCode >= 500:
+		return IsIdempotent(req)
+	}
+	return false
+}
+
+// IsIdempotent reports whether req may safely be sent more than once: its method is idempotent
+// (GET, HEAD, OPTIONS, TRACE, PUT, DELETE) or it carries an Idempotency-Key header.
+func IsIdempotent(req *http.Request) bool {
+	switch req.Method {
+	case http.MethodGet, http.MethodHead, http.MethodOptions, http.MethodTrace, http.MethodPut, http.MethodDelete:
+		return true
+	}
+	return req.Header.Get("Idempotency-Key") != "" || req.Header.Get("X-Idempotency-Key") != ""
+}
+
+// Backoff computes the wait before a retry.
+// retry is the number of the upcoming retry, starting at 1; prev is the previously returned delay (zero for the first retry).
+type Backoff interface {
+	Delay(retry int, prev time.Duration) time.Duration
+}
+
+// ConstantBackoff waits the same duration before every retry.
+type ConstantBackoff time.Duration
+
+// Delay implements Backoff.
+func (b ConstantBackoff) Delay(_ int, _ time.Duration) time.Duration {
+	return time.Duration(b)
+}
+
+// ExponentialBackoff waits Base * Multiplier^(retry-1), capped at Max.
+// Jitter is the fraction (0 to 1) of the delay that is randomized; 1 gives "full jitter".
+type ExponentialBackoff struct {
+	Base       time.Duration
+	Max        time.Duration
+	Multiplier float64
+	Jitter     float64
+}
+
+// Delay implements Backoff.
+func (b ExponentialBackoff) Delay(retry int, _ time.Duration) time.Duration {
+	multiplier := b.Multiplier
+	if multiplier <= 0 {
+		multiplier = 2
+	}
+	delay := float64(b.Base) * math.Pow(multiplier, float64(retry-1))
+	if b.Max > 0 && delay > float64(b.Max) {
+		delay = float64(b.Max)
+	}
+	if jitter := math.Min(math.Max(b.Jitter, 0), 1); jitter > 0 {
+		delay = delay*(1-jitter) + rand.Float64()*delay*jitter
+	}
+	return time.Duration(delay)
+}
+
+// DecorrelatedJitterBackoff waits a random duration between Base and three times the previous delay, capped at Max.
+type DecorrelatedJitterBackoff struct {
+	Base time.Duration
+	Max  time.Duration
+}
+
+// Delay implements Backoff.
+func (b DecorrelatedJitterBackoff) Delay(_ int, prev time.Duration) time.Duration {
+	if b.Base <= 0 {
+		return 0
+	}
+	upper := 3 * prev
+	if upper <= b.Base {
+		upper = 3 * b.Base
+	}
+	delay := b.Base + rand.N(upper-b.Base)
+	if b.Max > 0 && delay > b.Max {
+		delay = b.Max
+	}
+	return delay
+}
+
+// RetryConfig configures the RetryMiddlewareWithConfig.
+type RetryConfig struct {
+	// Attempts is the total number of attempts, including the first one. Defaults to 3.
+	Attempts int
+	// Policy decides whether an attempt is retried. Defaults to DefaultRetryPolicy.
+	Policy RetryPolicy
+	// Backoff computes the wait between attempts. Defaults to an exponential backoff starting at 100ms with jitter.
+	// A Retry-After header on 429 and 503 responses takes precedence.
+	Backoff Backoff
+	// MaxElapsed caps the total time spent on a request, including waits. Zero means no limit.
+	MaxElapsed time.Duration
+	// MaxRetryAfter is the longest Retry-After wait that is honored. If the server asks for a longer wait,
+	// the middleware gives up immediately with a *RetryError. Zero means no limit.
+	MaxRetryAfter time.Duration
+}
+
+// RetryError is returned by the retry middleware when it gives up on a request.
+// It wraps the last transport error, the context error if the request was cancelled while waiting,
+// or an *HTTPError describing the last response.
+type RetryError struct {
+	// Attempts is the number of attempts made.
+	Attempts int
+	// LastStatus is the status code of the last response, or zero if the last attempt failed without a response.
+	LastStatus int
+	// RetryAfter is the wait requested by the server, if it exceeded RetryConfig.MaxRetryAfter.
+	RetryAfter time.Duration
+	// Err is the underlying error.
+	Err error
+}
+
+// Error implements the error interface.
+func (e *RetryError) Error() string {
+	msg := fmt.Sprintf("giving up after %d attempt(s)", e.Attempts)
+	if e.LastStatus != 0 {
+		msg += fmt.Sprintf(", last status: %d", e.LastStatus)
+	}
+	if e.RetryAfter > 0 {
+		msg += fmt.Sprintf(", Retry-After of %s exceeds the maximum", e.RetryAfter)
+	}
+	if e.Err != nil {
+		msg += ": " + e.Err.Error()
+	}
+	return msg
+}
+
+// Unwrap returns the underlying error.
+func (e *RetryError) Unwrap() error {
+	return e.Err
+}
+
+// sleepContext waits for d or until ctx is done, whichever happens first.
+func sleepContext(ctx context.Context, d time.Duration) error {
+	if d <= 0 {
+		return ctx.Err()
+	}
+	timer := time.NewTimer(d)
+	defer timer.Stop()
+	select {
+	case <-ctx.Done():
+		return ctx.Err()
+	case <-timer.C:
+		return nil
+	}
+}
+
+// RetryMiddlewareWithConfig returns a middleware that retries requests as decided by the config's RetryPolicy,
+// waiting between attempts as computed by its Backoff.
+// Note: The request body is fully buffered in memory for retry purposes.
+func RetryMiddlewareWithConfig(config *RetryConfig) Middleware {
+	cfg := RetryConfig{}
+	if config != nil {
+		cfg = *config
+	}
+	if cfg.Attempts <= 0 {
+		cfg.Attempts = 3
+	}
+	if cfg.Policy == nil {
+		cfg.Policy = DefaultRetryPolicy
+	}
+	if cfg.Backoff == nil {
+		cfg.Backoff = ExponentialBackoff{Base: 100 * time.Millisecond, Max: 10 * time.Second, Multiplier: 2, Jitter: 0.2}
+	}
+	return func(next RoundTripFunc) RoundTripFunc {
+		return func(req *http.Request) (*http.Response, error) {
+			bodyBytes, err := bufferRequestBody(req)
+			if err != nil {
+				return nil, err
+			}
+
+			start := time.Now()
+			var delay time.Duration
+			for attempt := 1; ; attempt++ {
+				if req.Context().Err() != nil {
+					return nil, req.Context().Err()
+				}
+
+				recordAttempt(req.Context(), attempt)
+				attemptReq, attemptDone := startAttempt(cloneWithBody(req, bodyBytes), attempt)
+				resp, err := next(attemptReq)
+				attemptDone(resp, err)
+				if !cfg.Policy.ShouldRetry(req, resp, err, attempt) {
+					return resp, err
+				}
+
+				delay = cfg.Backoff.Delay(attempt, delay)
+				if resp != nil && (resp.StatusCode == http.StatusTooManyRequests || resp.StatusCode == http.StatusServiceUnavailable) {
+					if retryAfter := resp.Header.Get("Retry-After"); retryAfter != "" {
+						if d, parseErr := ParseRetryAfter(retryAfter, time.Now()); parseErr == nil {
+							if cfg.MaxRetryAfter > 0 && d > cfg.MaxRetryAfter {
+								return nil, &RetryError{Attempts: attempt, LastStatus: resp.StatusCode, RetryAfter: d, Err: newHTTPError(req, resp)}
+							}
+							delay = d
+						}
+					}
+				}
+
+				exhausted := attempt >= cfg.Attempts ||
+					(cfg.MaxElapsed > 0 && time.Since(start)+delay > cfg.MaxElapsed)
+				if exhausted {
+					if err != nil {
+						return nil, &RetryError{Attempts: attempt, Err: err}
+					}
+					return nil, &RetryError{Attempts: attempt, LastStatus: resp.StatusCode, Err: newHTTPError(req, resp)}
+				}
+				lastStatus := 0
+				if resp != nil {
+					lastStatus = resp.StatusCode
+					DrainAndClose(resp)
+				}
+				if err := sleepContext(req.Context(), delay); err != nil {
+					return nil, &RetryError{Attempts: attempt, LastStatus: lastStatus, Err: err}
+				}
+			}
+		}
+	}
+}
+
+// attemptHook observes a single attempt made by RetryMiddleware. It may modify req, which is a fresh copy
+// owned by the attempt, or return a derived request, and returns a function called with the attempt's outcome.
+type attemptHook func(req *http.Request, attempt int) (*http.Request, func(resp *http.Response, err error))
+
+// attemptHooksKey is the context key under which the attempt hooks of a request are stored.
+type attemptHooksKey struct{}
+
+// withAttemptHook returns a context in which RetryMiddleware calls hook for every attempt,
+// after the hooks already present in ctx.
+func withAttemptHook(ctx context.Context, hook attemptHook) context.Context {
+	hooks, _ := ctx.Value(attemptHooksKey{}).([]attemptHook)
+	return context.WithValue(ctx, attemptHooksKey{}, append(hooks[:len(hooks):len(hooks)], hook))
+}
+
+// withoutAttemptHooks returns a context without attempt hooks, so that the hooks of a request
+// are not run for the requests sent while handling it, such as token requests.
+func withoutAttemptHooks(ctx context.Context) context.Context {
+	if ctx.Value(attemptHooksKey{}) == nil {
+		return ctx
+	}
+	return context.WithValue(ctx, attemptHooksKey{}, []attemptHook(nil))
+}
+
+// startAttempt runs the attempt hooks of req's context and returns the request to send
+// and a function reporting the outcome to the hooks in reverse order.
+func startAttempt(req *http.Request, attempt int) (*http.Request, func(*http.Response, error)) {
+	hooks, _ := req.Context().Value(attemptHooksKey{}).([]attemptHook)
+	if len(hooks) == 0 {
+		return req, func(*http.Response, error) {}
+	}
+	dones := make([]func(*http.Response, error), len(hooks))
+	for i, hook := range hooks {
+		req, dones[i] = hook(req, attempt)
+	}
+	return req, func(resp *http.Response, err error) {
+		for i := len(dones) - 1; i >= 0; i-- {
+			dones[i](resp, err)
+		}
+	}
+}
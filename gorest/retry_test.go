@@ -0,0 +1,199 @@
+package gorest_test
+
+import (
+	"context"
+	"errors"
+	"io"
+	"net/http"
+	"strings"
+	"sync/atomic"
+	"time"
+
+	. "github.com/onsi/ginkgo/v2"
+	. "github.com/onsi/gomega"
+
+	"gorest/gorest"
+)
+
+var _ = Describe("Retry", func() {
+	// statusSequence returns a round trip that responds with the given statuses in order, repeating the last one.
+	statusSequence := func(calls *int32, statuses ...int) gorest.RoundTripFunc {
+		return func(req *http.Request) (*http.Response, error) {
+			n := int(atomic.AddInt32(calls, 1))
+			if n > len(statuses) {
+				n = len(statuses)
+			}
+			return &http.Response{
+				StatusCode: statuses[n-1],
+				Header:     http.Header{},
+				Body:       io.NopCloser(strings.NewReader("body")),
+			}, nil
+		}
+	}
+
+	Describe("DefaultRetryPolicy", func() {
+		It("should not retry non-idempotent requests on server errors", func() {
+			var calls int32
+			mw := gorest.RetryMiddlewareWithConfig(&gorest.RetryConfig{Attempts: 3, Backoff: gorest.ConstantBackoff(0)})
+			req, err := http.NewRequest("POST", "http://example.com", strings.NewReader("data"))
+			Expect(err).NotTo(HaveOccurred())
+			resp, err := mw(statusSequence(&calls, 500, 200))(req)
+			Expect(err).NotTo(HaveOccurred())
+			Expect(resp.StatusCode).To(Equal(500))
+			Expect(atomic.LoadInt32(&calls)).To(Equal(int32(1)))
+		})
+
+		It("should retry non-idempotent requests carrying an Idempotency-Key", func() {
+			var calls int32
+			mw := gorest.RetryMiddlewareWithConfig(&gorest.RetryConfig{Attempts: 3, Backoff: gorest.ConstantBackoff(0)})
+			req, err := http.NewRequest("POST", "http://example.com", strings.NewReader("data"))
+			Expect(err).NotTo(HaveOccurred())
+			req.Header.Set("Idempotency-Key", "abc")
+			resp, err := mw(statusSequence(&calls, 503, 200))(req)
+			Expect(err).NotTo(HaveOccurred())
+			Expect(resp.StatusCode).To(Equal(200))
+			Expect(atomic.LoadInt32(&calls)).To(Equal(int32(2)))
+		})
+
+		It("should retry any request on 429", func() {
+			var calls int32
+			mw := gorest.RetryMiddlewareWithConfig(&gorest.RetryConfig{Attempts: 3, Backoff: gorest.ConstantBackoff(0)})
+			req, err := http.NewRequest("POST", "http://example.com", nil)
+			Expect(err).NotTo(HaveOccurred())
+			resp, err := mw(statusSequence(&calls, 429, 201))(req)
+			Expect(err).NotTo(HaveOccurred())
+			Expect(resp.StatusCode).To(Equal(201))
+		})
+	})
+
+	It("should use a custom retry policy", func() {
+		var calls int32
+		var seen []int
+		policy := gorest.RetryPolicyFunc(func(req *http.Request, resp *http.Response, err error, attempt int) bool {
+			seen = append(seen, attempt)
+			return resp != nil && resp.StatusCode == http.StatusConflict
+		})
+		mw := gorest.RetryMiddlewareWithConfig(&gorest.RetryConfig{Attempts: 5, Policy: policy, Backoff: gorest.ConstantBackoff(0)})
+		req, err := http.NewRequest("GET", "http://example.com", nil)
+		Expect(err).NotTo(HaveOccurred())
+		resp, err := mw(statusSequence(&calls, 409, 409, 200))(req)
+		Expect(err).NotTo(HaveOccurred())
+		Expect(resp.StatusCode).To(Equal(200))
+		Expect(seen).To(Equal([]int{1, 2, 3}))
+	})
+
+	It("should stop when the total retry time would exceed MaxElapsed", func() {
+		var calls int32
+		mw := gorest.RetryMiddlewareWithConfig(&gorest.RetryConfig{
+			Attempts:   10,
+			Backoff:    gorest.ConstantBackoff(20 * time.Millisecond),
+			MaxElapsed: 50 * time.Millisecond,
+		})
+		req, err := http.NewRequest("GET", "http://example.com", nil)
+		Expect(err).NotTo(HaveOccurred())
+		_, err = mw(statusSequence(&calls, 500))(req)
+		Expect(err).To(HaveOccurred())
+		Expect(err.Error()).To(ContainSubstring("last status: 500"))
+		Expect(atomic.LoadInt32(&calls)).To(BeNumerically("<=", 3))
+	})
+
+	It("should wrap the last transport error when attempts are exhausted", func() {
+		mw := gorest.RetryMiddlewareWithConfig(&gorest.RetryConfig{Attempts: 2, Backoff: gorest.ConstantBackoff(0)})
+		req, err := http.NewRequest("GET", "http://example.com", nil)
+		Expect(err).NotTo(HaveOccurred())
+		req = req.WithContext(context.Background())
+		boom := errors.New("boom")
+		_, err = mw(func(*http.Request) (*http.Response, error) { return nil, boom })(req)
+		Expect(errors.Is(err, boom)).To(BeTrue())
+	})
+
+	Describe("waiting between attempts", func() {
+		It("should stop waiting as soon as the context is cancelled", func() {
+			var calls int32
+			mw := gorest.RetryMiddleware(3, time.Minute)
+			ctx, cancel := context.WithCancel(context.Background())
+			req, err := http.NewRequestWithContext(ctx, "GET", "http://example.com", nil)
+			Expect(err).NotTo(HaveOccurred())
+			time.AfterFunc(20*time.Millisecond, cancel)
+
+			start := time.Now()
+			_, err = mw(statusSequence(&calls, 503))(req)
+			Expect(time.Since(start)).To(BeNumerically("<", time.Second))
+			Expect(errors.Is(err, context.Canceled)).To(BeTrue())
+			var retryErr *gorest.RetryError
+			Expect(errors.As(err, &retryErr)).To(BeTrue())
+			Expect(retryErr.Attempts).To(Equal(1))
+			Expect(retryErr.LastStatus).To(Equal(503))
+		})
+
+		It("should give up when Retry-After exceeds MaxRetryAfter", func() {
+			var calls int32
+			rt := gorest.RoundTripFunc(func(req *http.Request) (*http.Response, error) {
+				atomic.AddInt32(&calls, 1)
+				return &http.Response{
+					StatusCode: 429,
+					Header:     http.Header{"Retry-After": {"120"}},
+					Body:       io.NopCloser(strings.NewReader("slow down")),
+				}, nil
+			})
+			mw := gorest.RetryMiddlewareWithConfig(&gorest.RetryConfig{Attempts: 3, MaxRetryAfter: time.Second})
+			req, err := http.NewRequest("GET", "http://example.com", nil)
+			Expect(err).NotTo(HaveOccurred())
+
+			_, err = mw(rt)(req)
+			var retryErr *gorest.RetryError
+			Expect(errors.As(err, &retryErr)).To(BeTrue())
+			Expect(retryErr.RetryAfter).To(Equal(120 * time.Second))
+			Expect(retryErr.LastStatus).To(Equal(429))
+			Expect(gorest.IsRateLimited(err)).To(BeTrue())
+			Expect(atomic.LoadInt32(&calls)).To(Equal(int32(1)))
+		})
+
+		It("should wait only once between attempts on server errors", func() {
+			var calls int32
+			mw := gorest.RetryMiddleware(3, 30*time.Millisecond)
+			req, err := http.NewRequest("GET", "http://example.com", nil)
+			Expect(err).NotTo(HaveOccurred())
+
+			start := time.Now()
+			_, err = mw(statusSequence(&calls, 500))(req)
+			Expect(time.Since(start)).To(BeNumerically("<", 110*time.Millisecond))
+			var retryErr *gorest.RetryError
+			Expect(errors.As(err, &retryErr)).To(BeTrue())
+			Expect(retryErr.Attempts).To(Equal(3))
+			Expect(retryErr.LastStatus).To(Equal(500))
+			Expect(gorest.IsServerError(err)).To(BeTrue())
+		})
+	})
+
+	Describe("Backoff", func() {
+		It("should grow exponentially up to the maximum", func() {
+			b := gorest.ExponentialBackoff{Base: 10 * time.Millisecond, Max: 50 * time.Millisecond, Multiplier: 2}
+			Expect(b.Delay(1, 0)).To(Equal(10 * time.Millisecond))
+			Expect(b.Delay(2, 0)).To(Equal(20 * time.Millisecond))
+			Expect(b.Delay(3, 0)).To(Equal(40 * time.Millisecond))
+			Expect(b.Delay(4, 0)).To(Equal(50 * time.Millisecond))
+		})
+
+		It("should keep jittered delays within bounds", func() {
+			b := gorest.ExponentialBackoff{Base: 100 * time.Millisecond, Multiplier: 2, Jitter: 0.5}
+			for i := 0; i < 50; i++ {
+				Expect(b.Delay(2, 0)).To(BeNumerically("~", 150*time.Millisecond, 50*time.Millisecond))
+			}
+		})
+
+		It("should keep decorrelated jitter delays between the base and the cap", func() {
+			b := gorest.DecorrelatedJitterBackoff{Base: 10 * time.Millisecond, Max: 100 * time.Millisecond}
+			var prev time.Duration
+			for i := 1; i <= 50; i++ {
+				prev = b.Delay(i, prev)
+				Expect(prev).To(BeNumerically(">=", 10*time.Millisecond))
+				Expect(prev).To(BeNumerically("<=", 100*time.Millisecond))
+			}
+		})
+
+		It("should return the same delay for constant backoff", func() {
+			Expect(gorest.ConstantBackoff(time.Second).Delay(5, time.Minute)).To(Equal(time.Second))
+		})
+	})
+})
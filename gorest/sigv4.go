@@ -0,0 +1,402 @@
+package gorest
+
+import (
+	"bytes"
+	"crypto/hmac"
+	"crypto/sha256"
+	"encoding/hex"
+	"errors"
+	"fmt"
+	"io"
+	"net/http"
+	"net/url"
+	"sort"
+	"strconv"
+	"strings"
+	"time"
+)
+
+const (
+	sigV4Algorithm      = "AWS4-HMAC-SHA256"
+	sigV4TimeFormat     = "20060102T150405Z"
+	sigV4DateFormat     = "20060102"
+	unsignedPayload     = "UNSIGNED-PAYLOAD"
+	streamingPayload    = "STREAMING-AWS4-HMAC-SHA256-PAYLOAD"
+	emptyPayloadSHA256  = "e3b0c44298fc1c149afbf4c8996fb92427ae41e4649b934ca495991b7852b855"
+	chunkSignaturePart  = ";chunk-signature="
+	sigV4SignatureBytes = 64
+)
+
+// AWSCredentials holds the credentials used to sign requests with SigV4.
+type AWSCredentials struct {
+	AccessKeyID     string
+	SecretAccessKey string
+	// SessionToken is set for temporary credentials.
+	SessionToken string
+}
+
+// SigV4Config configures AWS Signature Version 4 signing. It is required, with credentials, region and service.
+type SigV4Config struct {
+	Credentials AWSCredentials
+	// Region is the AWS region, such as "us-east-1".
+	Region string
+	// Service is the signing name of the service, such as "s3" or "execute-api".
+	Service string
+	// UnsignedPayload signs the request without hashing the body (S3 only).
+	UnsignedPayload bool
+	// ChunkSize, if positive, signs request bodies with the streaming aws-chunked encoding,
+	// in chunks of ChunkSize bytes (S3 only; at least 8 KiB except for the last chunk).
+	ChunkSize int
+	// SignContentSHA256 sends the payload hash in an X-Amz-Content-Sha256 header. It is always sent for
+	// the s3 service and with UnsignedPayload or ChunkSize.
+	SignContentSHA256 bool
+	// Now returns the signing time. Defaults to time.Now.
+	Now func() time.Time
+}
+
+// SigV4Middleware returns a middleware that signs requests with AWS Signature Version 4.
+// Each call signs the request with the current time, so place it after RetryMiddleware in WithMiddlewares
+// to re-sign every attempt. Unless the payload is unsigned or streamed, the request body is buffered
+// in memory to compute its hash. If the config is nil or incomplete, every request fails with an error.
+func SigV4Middleware(config *SigV4Config) Middleware {
+	cfg, configErr := config.withDefaults()
+	return func(next RoundTripFunc) RoundTripFunc {
+		return func(req *http.Request) (*http.Response, error) {
+			if configErr != nil {
+				return nil, configErr
+			}
+			signed, err := cfg.sign(req)
+			if err != nil {
+				return nil, err
+			}
+			return next(signed)
+		}
+	}
+}
+
+// PresignSigV4 returns a presigned URL for req that is valid for expires, as used to share S3 objects.
+// Only the host header is signed; the request body and other headers are ignored.
+// The request URL must be absolute (see Request.WithBaseURL).
+func PresignSigV4(req *Request, config *SigV4Config, expires time.Duration) (string, error) {
+	cfg, err := config.withDefaults()
+	if err != nil {
+		return "", err
+	}
+	httpReq, err := req.BuildHTTPRequest()
+	if err != nil {
+		return "", err
+	}
+	now := cfg.Now().UTC()
+	scope := cfg.scope(now)
+
+	query := httpReq.URL.Query()
+	query.Set("X-Amz-Algorithm", sigV4Algorithm)
+	query.Set("X-Amz-Credential", cfg.Credentials.AccessKeyID+"/"+scope)
+	query.Set("X-Amz-Date", now.Format(sigV4TimeFormat))
+	query.Set("X-Amz-Expires", strconv.Itoa(int(expires.Seconds())))
+	query.Set("X-Amz-SignedHeaders", "host")
+	if cfg.Credentials.SessionToken != "" {
+		query.Set("X-Amz-Security-Token", cfg.Credentials.SessionToken)
+	}
+	httpReq.URL.RawQuery = query.Encode()
+
+	payloadHash := emptyPayloadSHA256
+	if cfg.Service == "s3" {
+		payloadHash = unsignedPayload
+	}
+	canonical := strings.Join([]string{
+		httpReq.Method,
+		cfg.canonicalURI(httpReq.URL),
+		canonicalQuery(httpReq.URL),
+		"host:" + requestHost(httpReq) + "\n",
+		"host",
+		payloadHash,
+	}, "\n")
+	signature := cfg.signature(now, canonical)
+	return httpReq.URL.String() + "&X-Amz-Signature=" + signature, nil
+}
+
+// withDefaults validates the config and returns a copy with defaults applied.
+func (c *SigV4Config) withDefaults() (SigV4Config, error) {
+	if c == nil {
+		return SigV4Config{}, errors.New("sigv4: config is required")
+	}
+	if c.Credentials.AccessKeyID == "" || c.Credentials.SecretAccessKey == "" {
+		return SigV4Config{}, errors.New("sigv4: credentials are required")
+	}
+	if c.Region == "" || c.Service == "" {
+		return SigV4Config{}, errors.New("sigv4: region and service are required")
+	}
+	cfg := *c
+	if cfg.Now == nil {
+		cfg.Now = time.Now
+	}
+	return cfg, nil
+}
+
+// sign returns a signed copy of req.
+func (c *SigV4Config) sign(req *http.Request) (*http.Request, error) {
+	now := c.Now().UTC()
+	amzDate := now.Format(sigV4TimeFormat)
+	signed := req.Clone(req.Context())
+	signed.Header.Del("Authorization")
+	signed.Header.Set("X-Amz-Date", amzDate)
+	if c.Credentials.SessionToken != "" {
+		signed.Header.Set("X-Amz-Security-Token", c.Credentials.SessionToken)
+	}
+
+	hasBody := req.Body != nil && req.Body != http.NoBody
+	streaming := c.ChunkSize > 0 && hasBody
+	var payloadHash string
+	switch {
+	case streaming:
+		payloadHash = streamingPayload
+		if req.ContentLength <= 0 {
+			// The decoded length must be known up front, so read bodies of unknown length.
+			body, err := bufferRequestBody(req)
+			if err != nil {
+				return nil, err
+			}
+			signed = cloneWithBody(signed, body)
+		}
+		decodedLength := signed.ContentLength
+		signed.Header.Set("Content-Encoding", appendContentEncoding(signed.Header.Get("Content-Encoding")))
+		signed.Header.Set("X-Amz-Decoded-Content-Length", strconv.FormatInt(decodedLength, 10))
+		signed.ContentLength = chunkedLength(decodedLength, int64(c.ChunkSize))
+		signed.GetBody = nil
+	case c.UnsignedPayload:
+		payloadHash = unsignedPayload
+	case hasBody:
+		body, err := bufferRequestBody(req)
+		if err != nil {
+			return nil, err
+		}
+		signed = cloneWithBody(signed, body)
+		payloadHash = hexSHA256(body)
+	default:
+		payloadHash = emptyPayloadSHA256
+	}
+	if c.SignContentSHA256 || c.Service == "s3" || c.UnsignedPayload || streaming {
+		signed.Header.Set("X-Amz-Content-Sha256", payloadHash)
+	}
+
+	canonicalHeaders, signedHeaders := canonicalHeaders(signed, streaming)
+	canonical := strings.Join([]string{
+		signed.Method,
+		c.canonicalURI(signed.URL),
+		canonicalQuery(signed.URL),
+		canonicalHeaders,
+		signedHeaders,
+		payloadHash,
+	}, "\n")
+	signature := c.signature(now, canonical)
+	signed.Header.Set("Authorization", fmt.Sprintf("%s Credential=%s/%s, SignedHeaders=%s, Signature=%s",
+		sigV4Algorithm, c.Credentials.AccessKeyID, c.scope(now), signedHeaders, signature))
+
+	if streaming {
+		signed.Body = &chunkSigner{
+			src:       signed.Body,
+			cfg:       c,
+			now:       now,
+			prev:      signature,
+			chunkSize: c.ChunkSize,
+		}
+	}
+	return signed, nil
+}
+
+// scope returns the credential scope for the signing date.
+func (c *SigV4Config) scope(now time.Time) string {
+	return now.Format(sigV4DateFormat) + "/" + c.Region + "/" + c.Service + "/aws4_request"
+}
+
+// signature signs the canonical request.
+func (c *SigV4Config) signature(now time.Time, canonicalRequest string) string {
+	stringToSign := strings.Join([]string{
+		sigV4Algorithm,
+		now.Format(sigV4TimeFormat),
+		c.scope(now),
+		hexSHA256([]byte(canonicalRequest)),
+	}, "\n")
+	return hex.EncodeToString(hmacSHA256(c.signingKey(now), stringToSign))
+}
+
+func (c *SigV4Config) signingKey(now time.Time) []byte {
+	key := hmacSHA256([]byte("AWS4"+c.Credentials.SecretAccessKey), now.Format(sigV4DateFormat))
+	key = hmacSHA256(key, c.Region)
+	key = hmacSHA256(key, c.Service)
+	return hmacSHA256(key, "aws4_request")
+}
+
+// canonicalURI encodes the URL path. Services other than S3 expect each segment to be encoded twice.
+func (c *SigV4Config) canonicalURI(u *url.URL) string {
+	path := u.Path
+	if path == "" {
+		path = "/"
+	}
+	encoded := encodeSigV4Path(path)
+	if c.Service != "s3" {
+		encoded = encodeSigV4Path(encoded)
+	}
+	return encoded
+}
+
+func encodeSigV4Path(path string) string {
+	segments := strings.Split(path, "/")
+	for i, segment := range segments {
+		segments[i] = sigV4Escape(segment)
+	}
+	return strings.Join(segments, "/")
+}
+
+// canonicalQuery returns the query parameters encoded and sorted by name, then value.
+func canonicalQuery(u *url.URL) string {
+	var pairs []string
+	for name, values := range u.Query() {
+		for _, value := range values {
+			pairs = append(pairs, sigV4Escape(name)+"="+sigV4Escape(value))
+		}
+	}
+	sort.Strings(pairs)
+	return strings.Join(pairs, "&")
+}
+
+// sigV4IgnoredHeaders are not signed since proxies and transports may change them.
+var sigV4IgnoredHeaders = map[string]bool{
+	"authorization":   true,
+	"user-agent":      true,
+	"x-amzn-trace-id": true,
+	"expect":          true,
+	"connection":      true,
+}
+
+// canonicalHeaders returns the canonical headers block and the signed headers list.
+// The host header is always signed, as is the content length of streamed bodies.
+func canonicalHeaders(req *http.Request, withContentLength bool) (string, string) {
+	headers := map[string]string{"host": requestHost(req)}
+	if withContentLength {
+		headers["content-length"] = strconv.FormatInt(req.ContentLength, 10)
+	}
+	for name, values := range req.Header {
+		lower := strings.ToLower(name)
+		if sigV4IgnoredHeaders[lower] {
+			continue
+		}
+		trimmed := make([]string, len(values))
+		for i, v := range values {
+			trimmed[i] = strings.Join(strings.Fields(v), " ")
+		}
+		headers[lower] = strings.Join(trimmed, ",")
+	}
+	names := sortedKeys(headers)
+	var b strings.Builder
+	for _, name := range names {
+		b.WriteString(name + ":" + headers[name] + "\n")
+	}
+	return b.String(), strings.Join(names, ";")
+}
+
+func requestHost(req *http.Request) string {
+	if req.Host != "" {
+		return req.Host
+	}
+	return req.URL.Host
+}
+
+// sigV4Escape percent-encodes everything except the unreserved characters of RFC 3986.
+func sigV4Escape(s string) string {
+	var b strings.Builder
+	for i := 0; i < len(s); i++ {
+		c := s[i]
+		if 'A' <= c && c <= 'Z' || 'a' <= c && c <= 'z' || '0' <= c && c <= '9' || c == '-' || c == '_' || c == '.' || c == '~' {
+			b.WriteByte(c)
+		} else {
+			fmt.Fprintf(&b, "%%%02X", c)
+		}
+	}
+	return b.String()
+}
+
+func appendContentEncoding(existing string) string {
+	if existing == "" {
+		return "aws-chunked"
+	}
+	return "aws-chunked," + existing
+}
+
+func hexSHA256(data []byte) string {
+	sum := sha256.Sum256(data)
+	return hex.EncodeToString(sum[:])
+}
+
+func hmacSHA256(key []byte, data string) []byte {
+	mac := hmac.New(sha256.New, key)
+	mac.Write([]byte(data))
+	return mac.Sum(nil)
+}
+
+// chunkedLength returns the length of a body of decodedLength bytes in the aws-chunked encoding.
+func chunkedLength(decodedLength, chunkSize int64) int64 {
+	chunkLength := func(n int64) int64 {
+		return int64(len(strconv.FormatInt(n, 16))) + int64(len(chunkSignaturePart)) + sigV4SignatureBytes + 2 + n + 2
+	}
+	full := decodedLength / chunkSize
+	length := full*chunkLength(chunkSize) + chunkLength(0)
+	if rest := decodedLength % chunkSize; rest > 0 {
+		length += chunkLength(rest)
+	}
+	return length
+}
+
+// chunkSigner encodes a body in signed aws-chunked chunks, each signature chaining the previous one.
+type chunkSigner struct {
+	src       io.ReadCloser
+	cfg       *SigV4Config
+	now       time.Time
+	prev      string
+	chunkSize int
+	buf       bytes.Buffer
+	done      bool
+}
+
+func (s *chunkSigner) Read(p []byte) (int, error) {
+	for s.buf.Len() == 0 {
+		if s.done {
+			return 0, io.EOF
+		}
+		if err := s.nextChunk(); err != nil {
+			return 0, err
+		}
+	}
+	return s.buf.Read(p)
+}
+
+// nextChunk reads and encodes the next chunk, or the final empty chunk at the end of the body.
+func (s *chunkSigner) nextChunk() error {
+	data := make([]byte, s.chunkSize)
+	n, err := io.ReadFull(s.src, data)
+	if err != nil && err != io.EOF && err != io.ErrUnexpectedEOF {
+		return err
+	}
+	data = data[:n]
+	stringToSign := strings.Join([]string{
+		"AWS4-HMAC-SHA256-PAYLOAD",
+		s.now.Format(sigV4TimeFormat),
+		s.cfg.scope(s.now),
+		s.prev,
+		emptyPayloadSHA256,
+		hexSHA256(data),
+	}, "\n")
+	s.prev = hex.EncodeToString(hmacSHA256(s.cfg.signingKey(s.now), stringToSign))
+	fmt.Fprintf(&s.buf, "%x%s%s\r\n", n, chunkSignaturePart, s.prev)
+	s.buf.Write(data)
+	s.buf.WriteString("\r\n")
+	if n == 0 {
+		s.done = true
+	}
+	return nil
+}
+
+func (s *chunkSigner) Close() error {
+	return s.src.Close()
+}
@@ -0,0 +1,196 @@
+package gorest
+
+import (
+	"bytes"
+	"io"
+	"log/slog"
+	"math/rand/v2"
+	"net/http"
+	"slices"
+	"strings"
+	"sync"
+	"sync/atomic"
+	"time"
+)
+
+// SlogConfig configures the SlogMiddleware.
+type SlogConfig struct {
+	// Message is the message of the log records. Defaults to "http request".
+	Message string
+	// LogHeaders includes the request and response headers in the record.
+	LogHeaders bool
+	// RedactHeaders lists the headers whose values are replaced with "[REDACTED]".
+	// Defaults to Authorization, Proxy-Authorization, Cookie, Set-Cookie and X-Api-Key.
+	RedactHeaders []string
+	// MaxBodySize is the number of bytes of the request and response bodies included in the record.
+	// Bodies are not logged when zero.
+	MaxBodySize int
+	// RedactBody, if set, is applied to the logged bodies.
+	RedactBody func(body string) string
+	// Level returns the level of the record for an exchange. Defaults to Error for transport errors and 5xx
+	// responses, Warn for 4xx responses and Info otherwise.
+	Level func(resp *http.Response, err error) slog.Level
+	// SampleRate is the fraction of exchanges logged below the Warn level, between 0 and 1.
+	// Zero logs every exchange.
+	SampleRate float64
+}
+
+// DefaultSlogLevel is the default SlogConfig.Level.
+func DefaultSlogLevel(resp *http.Response, err error) slog.Level {
+	switch {
+	case err != nil || resp.StatusCode >= 500:
+		return slog.LevelError
+	case resp.StatusCode >= 400:
+		return slog.LevelWarn
+	default:
+		return slog.LevelInfo
+	}
+}
+
+// SlogMiddleware returns a middleware that logs one structured record per exchange with logger, with the
+// method, URL, status, duration, body sizes and number of attempts. The record is emitted when the response
+// body is closed, so the duration and response size cover the whole exchange, or when the request fails.
+// Place it before RetryMiddleware in WithMiddlewares so that attempts are counted within a single record.
+// Warning: Logged headers and bodies may include sensitive data not covered by the redaction settings.
+func SlogMiddleware(logger *slog.Logger, config *SlogConfig) Middleware {
+	cfg := SlogConfig{}
+	if config != nil {
+		cfg = *config
+	}
+	if cfg.Message == "" {
+		cfg.Message = "http request"
+	}
+	if cfg.RedactHeaders == nil {
+		cfg.RedactHeaders = []string{"Authorization", "Proxy-Authorization", "Cookie", "Set-Cookie", "X-Api-Key"}
+	}
+	if cfg.RedactBody == nil {
+		cfg.RedactBody = func(s string) string { return s }
+	}
+	if cfg.Level == nil {
+		cfg.Level = DefaultSlogLevel
+	}
+	redacted := make(map[string]bool, len(cfg.RedactHeaders))
+	for _, name := range cfg.RedactHeaders {
+		redacted[http.CanonicalHeaderKey(name)] = true
+	}
+
+	return func(next RoundTripFunc) RoundTripFunc {
+		return func(req *http.Request) (*http.Response, error) {
+			var attempts atomic.Int32
+			attempts.Store(1)
+			logged := req.WithContext(withAttemptHook(req.Context(), func(attemptReq *http.Request, attempt int) (*http.Request, func(*http.Response, error)) {
+				attempts.Store(int32(attempt))
+				return attemptReq, func(*http.Response, error) {}
+			}))
+			sent := &capturingReadCloser{ReadCloser: req.Body, max: cfg.MaxBodySize}
+			if req.Body != nil && req.Body != http.NoBody {
+				logged.Body = sent
+			}
+
+			start := time.Now()
+			resp, err := next(logged)
+			received := &capturingReadCloser{max: cfg.MaxBodySize}
+			emit := func() {
+				level := cfg.Level(resp, err)
+				if level < slog.LevelWarn && cfg.SampleRate > 0 && rand.Float64() >= cfg.SampleRate {
+					return
+				}
+				ctx := req.Context()
+				if !logger.Enabled(ctx, level) {
+					return
+				}
+				attrs := []slog.Attr{
+					slog.String("method", req.Method),
+					slog.String("url", req.URL.Redacted()),
+				}
+				if template := URLTemplate(req); template != "" {
+					attrs = append(attrs, slog.String("route", template))
+				}
+				if resp != nil {
+					attrs = append(attrs, slog.Int("status", resp.StatusCode))
+				}
+				attrs = append(attrs,
+					slog.Duration("duration", time.Since(start)),
+					slog.Int64("request_bytes", sent.count()),
+					slog.Int64("response_bytes", received.count()),
+					slog.Int("attempts", int(attempts.Load())),
+				)
+				if err != nil {
+					attrs = append(attrs, slog.String("error", err.Error()))
+				}
+				if cfg.LogHeaders {
+					attrs = append(attrs, headerGroup("request_headers", req.Header, redacted))
+					if resp != nil {
+						attrs = append(attrs, headerGroup("response_headers", resp.Header, redacted))
+					}
+				}
+				if cfg.MaxBodySize > 0 {
+					attrs = append(attrs,
+						slog.String("request_body", cfg.RedactBody(sent.captured())),
+						slog.String("response_body", cfg.RedactBody(received.captured())),
+					)
+				}
+				logger.LogAttrs(ctx, level, cfg.Message, attrs...)
+			}
+
+			if err != nil || resp.Body == nil {
+				emit()
+				return resp, err
+			}
+			received.ReadCloser = resp.Body
+			resp.Body = &releaseOnClose{ReadCloser: received, release: sync.OnceFunc(emit)}
+			return resp, nil
+		}
+	}
+}
+
+// headerGroup returns the headers as a group of attributes, replacing the values of redacted headers.
+func headerGroup(name string, header http.Header, redacted map[string]bool) slog.Attr {
+	keys := make([]string, 0, len(header))
+	for key := range header {
+		keys = append(keys, key)
+	}
+	slices.Sort(keys)
+	attrs := make([]any, 0, len(keys))
+	for _, key := range keys {
+		value := strings.Join(header[key], ", ")
+		if redacted[http.CanonicalHeaderKey(key)] {
+			value = "[REDACTED]"
+		}
+		attrs = append(attrs, slog.String(key, value))
+	}
+	return slog.Group(name, attrs...)
+}
+
+// capturingReadCloser counts the bytes read from the underlying ReadCloser and keeps the first max of them.
+type capturingReadCloser struct {
+	io.ReadCloser
+	max int
+
+	mu  sync.Mutex
+	n   int64
+	buf bytes.Buffer
+}
+
+func (c *capturingReadCloser) Read(p []byte) (int, error) {
+	n, err := c.ReadCloser.Read(p)
+	c.mu.Lock()
+	defer c.mu.Unlock()
+	c.n += int64(n)
+	if room := c.max - c.buf.Len(); room > 0 {
+		c.buf.Write(p[:min(n, room)])
+	}
+	return n, err
+}
+
+func (c *capturingReadCloser) count() int64 {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+	return c.n
+}
+
+func (c *capturingReadCloser) captured() string {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+	return c.buf.String()
+}
@@ -0,0 +1,160 @@
+package gorest_test
+
+import (
+	"bytes"
+	"context"
+	"encoding/json"
+	"errors"
+	"io"
+	"log/slog"
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"sync/atomic"
+
+	. "github.com/onsi/ginkgo/v2"
+	. "github.com/onsi/gomega"
+
+	"gorest/gorest"
+)
+
+var _ = Describe("SlogMiddleware", func() {
+	var (
+		out    *bytes.Buffer
+		logger *slog.Logger
+		calls  int32
+		server *httptest.Server
+	)
+
+	records := func() []map[string]any {
+		var result []map[string]any
+		for _, line := range strings.Split(strings.TrimSpace(out.String()), "\n") {
+			if line == "" {
+				continue
+			}
+			var record map[string]any
+			Expect(json.Unmarshal([]byte(line), &record)).To(Succeed())
+			result = append(result, record)
+		}
+		return result
+	}
+
+	BeforeEach(func() {
+		out = &bytes.Buffer{}
+		logger = slog.New(slog.NewJSONHandler(out, &slog.HandlerOptions{Level: slog.LevelDebug}))
+		calls = 0
+		server = httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+			_, _ = io.Copy(io.Discard, r.Body)
+			w.Header().Set("Set-Cookie", "session=secret")
+			switch {
+			case r.URL.Path == "/flaky" && atomic.AddInt32(&calls, 1) < 2:
+				w.WriteHeader(http.StatusServiceUnavailable)
+			case r.URL.Path == "/missing":
+				w.WriteHeader(http.StatusNotFound)
+			case r.URL.Path == "/broken":
+				w.WriteHeader(http.StatusInternalServerError)
+			}
+			_, _ = w.Write([]byte(`{"token":"abc","name":"gopher"}`))
+		}))
+	})
+
+	AfterEach(func() {
+		server.Close()
+	})
+
+	It("should log one record per exchange", func() {
+		client := gorest.NewClient(gorest.WithBaseURL(server.URL), gorest.WithMiddlewares(gorest.SlogMiddleware(logger, nil)))
+		_, err := client.Do(context.Background(), gorest.NewRequest("POST", "/users/{id}").WithPathParam("id", "1").WithBody([]byte("hello")))
+		Expect(err).NotTo(HaveOccurred())
+
+		logged := records()
+		Expect(logged).To(HaveLen(1))
+		record := logged[0]
+		Expect(record["level"]).To(Equal("INFO"))
+		Expect(record["msg"]).To(Equal("http request"))
+		Expect(record["method"]).To(Equal("POST"))
+		Expect(record["url"]).To(Equal(server.URL + "/users/1"))
+		Expect(record["route"]).To(Equal("/users/{id}"))
+		Expect(record["status"]).To(BeEquivalentTo(200))
+		Expect(record["request_bytes"]).To(BeEquivalentTo(5))
+		Expect(record["response_bytes"]).To(BeEquivalentTo(31))
+		Expect(record["attempts"]).To(BeEquivalentTo(1))
+		Expect(record).To(HaveKey("duration"))
+		Expect(record).NotTo(HaveKey("request_headers"))
+		Expect(record).NotTo(HaveKey("response_body"))
+	})
+
+	It("should include redacted headers and bodies", func() {
+		client := gorest.NewClient(gorest.WithMiddlewares(gorest.SlogMiddleware(logger, &gorest.SlogConfig{
+			LogHeaders:  true,
+			MaxBodySize: 20,
+			RedactBody: func(body string) string {
+				return strings.ReplaceAll(body, "abc", "***")
+			},
+		})))
+		_, err := client.Do(context.Background(), gorest.NewRequest("PUT", server.URL).
+			WithHeader("Authorization", "Bearer t0ken").
+			WithHeader("X-Request-Id", "42").
+			WithBody([]byte("payload")))
+		Expect(err).NotTo(HaveOccurred())
+
+		record := records()[0]
+		Expect(record["request_headers"]).To(HaveKeyWithValue("Authorization", "[REDACTED]"))
+		Expect(record["request_headers"]).To(HaveKeyWithValue("X-Request-Id", "42"))
+		Expect(record["response_headers"]).To(HaveKeyWithValue("Set-Cookie", "[REDACTED]"))
+		Expect(record["request_body"]).To(Equal("payload"))
+		Expect(record["response_body"]).To(Equal(`{"token":"***","name`))
+		Expect(out.String()).NotTo(ContainSubstring("t0ken"))
+	})
+
+	It("should select the level by status class", func() {
+		client := gorest.NewClient(gorest.WithMiddlewares(gorest.SlogMiddleware(logger, nil)))
+		for _, path := range []string{"/missing", "/broken"} {
+			_, err := client.Do(context.Background(), gorest.NewRequest("GET", server.URL+path))
+			Expect(err).NotTo(HaveOccurred())
+		}
+		logged := records()
+		Expect(logged[0]["level"]).To(Equal("WARN"))
+		Expect(logged[1]["level"]).To(Equal("ERROR"))
+	})
+
+	It("should log transport errors", func() {
+		failing := func(next gorest.RoundTripFunc) gorest.RoundTripFunc {
+			return func(req *http.Request) (*http.Response, error) {
+				return nil, errors.New("connection refused")
+			}
+		}
+		client := gorest.NewClient(gorest.WithMiddlewares(gorest.SlogMiddleware(logger, nil), failing))
+		_, err := client.Do(context.Background(), gorest.NewRequest("GET", server.URL))
+		Expect(err).To(HaveOccurred())
+		record := records()[0]
+		Expect(record["level"]).To(Equal("ERROR"))
+		Expect(record["error"]).To(Equal("connection refused"))
+		Expect(record).NotTo(HaveKey("status"))
+	})
+
+	It("should count retry attempts", func() {
+		client := gorest.NewClient(gorest.WithMiddlewares(
+			gorest.SlogMiddleware(logger, nil),
+			gorest.RetryMiddlewareWithConfig(&gorest.RetryConfig{Attempts: 3, Backoff: gorest.ConstantBackoff(0)}),
+		))
+		_, err := client.Do(context.Background(), gorest.NewRequest("GET", server.URL+"/flaky"))
+		Expect(err).NotTo(HaveOccurred())
+		logged := records()
+		Expect(logged).To(HaveLen(1))
+		Expect(logged[0]["attempts"]).To(BeEquivalentTo(2))
+	})
+
+	It("should sample successful exchanges but keep failures", func() {
+		client := gorest.NewClient(gorest.WithMiddlewares(gorest.SlogMiddleware(logger, &gorest.SlogConfig{SampleRate: 0.000001})))
+		for i := 0; i < 20; i++ {
+			_, err := client.Do(context.Background(), gorest.NewRequest("GET", server.URL))
+			Expect(err).NotTo(HaveOccurred())
+		}
+		_, err := client.Do(context.Background(), gorest.NewRequest("GET", server.URL+"/broken"))
+		Expect(err).NotTo(HaveOccurred())
+		logged := records()
+		Expect(logged).To(HaveLen(1))
+		Expect(logged[0]["status"]).To(BeEquivalentTo(500))
+	})
+})
@@ -0,0 +1,268 @@
+package gorest
+
+import (
+	"bufio"
+	"bytes"
+	"context"
+	"errors"
+	"fmt"
+	"io"
+	"iter"
+	"mime"
+	"net/http"
+	"strconv"
+	"strings"
+	"time"
+)
+
+// maxEventLineSize limits the length of a single line in an event stream.
+const maxEventLineSize = 1 << 20
+
+// Event is a single Server-Sent Event parsed from a text/event-stream response.
+type Event struct {
+	// ID is the last event ID seen on the stream when the event was dispatched.
+	ID string
+	// Event is the event type. It defaults to "message".
+	Event string
+	// Data holds the event payload; multiple data lines are joined with "\n".
+	Data string
+	// Retry is the reconnection time requested by the server in this event block, if any.
+	Retry time.Duration
+}
+
+// SSE parses the response body as a text/event-stream and yields each dispatched event.
+// Iteration stops at the end of the stream or at the first read error, which is yielded.
+// The response body is closed when iteration finishes, including when the caller stops early.
+func (r *Response) SSE() iter.Seq2[Event, error] {
+	return func(yield func(Event, error) bool) {
+		defer func() {
+			_ = r.Close()
+		}()
+		parser := newEventParser(r.Body, "")
+		for {
+			ev, err := parser.next()
+			if err == io.EOF {
+				return
+			}
+			if err != nil {
+				yield(Event{}, err)
+				return
+			}
+			if !yield(ev, nil) {
+				return
+			}
+		}
+	}
+}
+
+// SSEConfig configures Client.Subscribe.
+type SSEConfig struct {
+	// LastEventID is sent as the Last-Event-ID header on the first connection, to resume a previous subscription.
+	LastEventID string
+	// ReconnectDelay is the wait before reconnecting until the server sends a retry field. Defaults to 3 seconds.
+	ReconnectDelay time.Duration
+	// MaxReconnects limits consecutive reconnections without receiving an event.
+	// Zero means unlimited; a negative value disables reconnection.
+	MaxReconnects int
+}
+
+// Subscribe opens a Server-Sent Events stream for req and yields its events.
+// When the stream ends or the connection fails, Subscribe reconnects after the server's retry delay,
+// sending the last received event ID in the Last-Event-ID header. Connection failures are yielded
+// before reconnecting; the caller may stop iterating or continue to keep the subscription going.
+// Iteration stops when ctx is done, when the caller stops early, when the server responds with
+// 204 No Content, or when a terminal error is yielded (an invalid request, a non-2xx status, a non
+// event-stream response, a malformed stream such as a line over 1 MiB, or too many reconnections).
+// Note: The client timeout applies to the whole stream; use WithTimeout(0) for long-lived subscriptions.
+func (c *Client) Subscribe(ctx context.Context, req *Request, config *SSEConfig) iter.Seq2[Event, error] {
+	cfg := SSEConfig{}
+	if config != nil {
+		cfg = *config
+	}
+	if cfg.ReconnectDelay <= 0 {
+		cfg.ReconnectDelay = 3 * time.Second
+	}
+	return func(yield func(Event, error) bool) {
+		lastID := cfg.LastEventID
+		delay := cfg.ReconnectDelay
+		reconnects := 0
+		for {
+			attempt := req.clone().
+				WithHeader("Accept", "text/event-stream").
+				WithHeader("Cache-Control", "no-cache")
+			if lastID != "" {
+				attempt.WithHeader("Last-Event-ID", lastID)
+			}
+
+			httpReq, err := attempt.buildHTTPRequest(c.baseURL, c.codecs)
+			if err != nil {
+				// Invalid requests fail the same way on every reconnection.
+				yield(Event{}, err)
+				return
+			}
+			resp, err := c.send(ctx, attempt, httpReq)
+			if err == nil {
+				if resp.StatusCode == http.StatusNoContent {
+					DrainAndClose(resp)
+					return
+				}
+				if resp.StatusCode < 200 || resp.StatusCode >= 300 {
+					yield(Event{}, newHTTPError(resp.Request, resp))
+					return
+				}
+				if mediaType, _, _ := mime.ParseMediaType(resp.Header.Get("Content-Type")); mediaType != "text/event-stream" {
+					DrainAndClose(resp)
+					yield(Event{}, fmt.Errorf("unexpected content type for event stream: %q", resp.Header.Get("Content-Type")))
+					return
+				}
+
+				parser := newEventParser(resp.Body, lastID)
+				for {
+					var ev Event
+					ev, err = parser.next()
+					if err != nil {
+						break
+					}
+					reconnects = 0
+					if !yield(ev, nil) {
+						_ = resp.Body.Close()
+						return
+					}
+				}
+				if isScannerError(err) {
+					// Reconnecting would most likely fail on the same event again.
+					_ = resp.Body.Close()
+					yield(Event{}, err)
+					return
+				}
+				lastID = parser.lastID
+				if parser.retry > 0 {
+					delay = parser.retry
+				}
+				_ = resp.Body.Close()
+			}
+
+			if ctx.Err() != nil {
+				yield(Event{}, ctx.Err())
+				return
+			}
+			reconnects++
+			if cfg.MaxReconnects < 0 || (cfg.MaxReconnects > 0 && reconnects > cfg.MaxReconnects) {
+				yield(Event{}, fmt.Errorf("event stream closed after %d reconnect(s): %w", reconnects-1, err))
+				return
+			}
+			if err != io.EOF && !yield(Event{}, err) {
+				return
+			}
+			if err := sleepContext(ctx, delay); err != nil {
+				yield(Event{}, err)
+				return
+			}
+		}
+	}
+}
+
+// eventParser parses a text/event-stream as described by the HTML Living Standard.
+type eventParser struct {
+	scanner *bufio.Scanner
+	// lastID is the last event ID buffer; it persists across events.
+	lastID string
+	// retry is the last reconnection time sent by the server.
+	retry time.Duration
+}
+
+func newEventParser(r io.Reader, lastID string) *eventParser {
+	scanner := bufio.NewScanner(r)
+	scanner.Buffer(make([]byte, 4096), maxEventLineSize)
+	scanner.Split(scanEventLines)
+	return &eventParser{scanner: scanner, lastID: lastID}
+}
+
+// next returns the next dispatched event, or io.EOF at the end of the stream.
+// An incomplete event at the end of the stream is discarded.
+func (p *eventParser) next() (Event, error) {
+	var (
+		data    strings.Builder
+		hasData bool
+		ev      Event
+	)
+	for p.scanner.Scan() {
+		line := p.scanner.Text()
+		if line == "" {
+			if !hasData {
+				ev = Event{}
+				continue
+			}
+			ev.ID = p.lastID
+			ev.Data = data.String()
+			if ev.Event == "" {
+				ev.Event = "message"
+			}
+			return ev, nil
+		}
+		if strings.HasPrefix(line, ":") {
+			continue
+		}
+		field, value, _ := strings.Cut(line, ":")
+		value = strings.TrimPrefix(value, " ")
+		switch field {
+		case "data":
+			if hasData {
+				data.WriteByte('\n')
+			}
+			data.WriteString(value)
+			hasData = true
+		case "event":
+			ev.Event = value
+		case "id":
+			if !strings.ContainsRune(value, 0) {
+				p.lastID = value
+			}
+		case "retry":
+			if ms, err := strconv.ParseUint(value, 10, 63); err == nil {
+				p.retry = time.Duration(ms) * time.Millisecond
+				ev.Retry = p.retry
+			}
+		}
+	}
+	if err := p.scanner.Err(); err != nil {
+		if errors.Is(err, bufio.ErrTooLong) {
+			return Event{}, fmt.Errorf("event stream line exceeds %d bytes: %w", maxEventLineSize, err)
+		}
+		return Event{}, err
+	}
+	return Event{}, io.EOF
+}
+
+// isScannerError reports whether err was raised while splitting the stream rather than by the underlying reader.
+func isScannerError(err error) bool {
+	return errors.Is(err, bufio.ErrTooLong) || errors.Is(err, bufio.ErrNegativeAdvance) ||
+		errors.Is(err, bufio.ErrAdvanceTooFar) || errors.Is(err, bufio.ErrBadReadCount)
+}
+
+// scanEventLines is a bufio.SplitFunc that splits lines terminated by "\r\n", "\n" or "\r".
+func scanEventLines(data []byte, atEOF bool) (advance int, token []byte, err error) {
+	if atEOF && len(data) == 0 {
+		return 0, nil, nil
+	}
+	if i := bytes.IndexAny(data, "\r\n"); i >= 0 {
+		if data[i] == '\n' {
+			return i + 1, data[:i], nil
+		}
+		if i+1 < len(data) {
+			if data[i+1] == '\n' {
+				return i + 2, data[:i], nil
+			}
+			return i + 1, data[:i], nil
+		}
+		if atEOF {
+			return i + 1, data[:i], nil
+		}
+		// Wait for the next byte to tell "\r" from "\r\n".
+		return 0, nil, nil
+	}
+	if atEOF {
+		return len(data), data, nil
+	}
+	return 0, nil, nil
+}
@@ -0,0 +1,203 @@
+package gorest_test
+
+import (
+	"bufio"
+	"context"
+	"errors"
+	"fmt"
+	"io"
+	"net/http"
+	"net/http/httptest"
+	"net/url"
+	"strings"
+	"sync/atomic"
+	"time"
+
+	. "github.com/onsi/ginkgo/v2"
+	. "github.com/onsi/gomega"
+
+	"gorest/gorest"
+)
+
+var _ = Describe("Server-Sent Events", func() {
+	It("should parse an event stream into events", func() {
+		stream := ": comment\r\n" +
+			"retry: 1500\r\n" +
+			"id: 1\r\n" +
+			"event: greeting\r\n" +
+			"data: hello\r\n" +
+			"data: world\r\n" +
+			"\r\n" +
+			"data:no space\n" +
+			"\n" +
+			"id: 2\rdata: cr only\r\r" +
+			"data: incomplete"
+		response := &gorest.Response{Response: &http.Response{
+			StatusCode: 200,
+			Header:     http.Header{"Content-Type": {"text/event-stream"}},
+			Body:       io.NopCloser(strings.NewReader(stream)),
+		}}
+
+		var events []gorest.Event
+		for ev, err := range response.SSE() {
+			Expect(err).NotTo(HaveOccurred())
+			events = append(events, ev)
+		}
+		Expect(events).To(Equal([]gorest.Event{
+			{ID: "1", Event: "greeting", Data: "hello\nworld", Retry: 1500 * time.Millisecond},
+			{ID: "1", Event: "message", Data: "no space"},
+			{ID: "2", Event: "message", Data: "cr only"},
+		}))
+	})
+
+	It("should close the body when the caller stops early", func() {
+		closed := false
+		response := &gorest.Response{Response: &http.Response{
+			StatusCode: 200,
+			Body: &dummyReadCloser{
+				Reader:    strings.NewReader("data: a\n\ndata: b\n\n"),
+				closeFunc: func() error { closed = true; return nil },
+			},
+		}}
+		for ev := range response.SSE() {
+			Expect(ev.Data).To(Equal("a"))
+			break
+		}
+		Expect(closed).To(BeTrue())
+	})
+
+	Describe("Client.Subscribe", func() {
+		It("should reconnect with Last-Event-ID after the stream ends", func() {
+			var connections int32
+			var lastEventIDs []string
+			server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+				n := atomic.AddInt32(&connections, 1)
+				lastEventIDs = append(lastEventIDs, r.Header.Get("Last-Event-ID"))
+				Expect(r.Header.Get("Accept")).To(Equal("text/event-stream"))
+				w.Header().Set("Content-Type", "text/event-stream")
+				if n == 1 {
+					_, _ = fmt.Fprint(w, "retry: 10\n\nid: 1\ndata: first\n\nid: 2\ndata: second\n\n")
+					return
+				}
+				_, _ = fmt.Fprint(w, "id: 3\ndata: third\n\n")
+			}))
+			defer server.Close()
+
+			client := gorest.NewClient()
+			var data []string
+			for ev, err := range client.Subscribe(context.Background(), gorest.NewRequest("GET", server.URL), nil) {
+				Expect(err).NotTo(HaveOccurred())
+				data = append(data, ev.Data)
+				if len(data) == 3 {
+					break
+				}
+			}
+			Expect(data).To(Equal([]string{"first", "second", "third"}))
+			Expect(lastEventIDs).To(Equal([]string{"", "2"}))
+		})
+
+		It("should resend the form body on every connection", func() {
+			var bodies []string
+			server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+				body, _ := io.ReadAll(r.Body)
+				bodies = append(bodies, string(body))
+				w.Header().Set("Content-Type", "text/event-stream")
+			}))
+			defer server.Close()
+
+			req := gorest.NewRequest("POST", server.URL).WithFormBody(url.Values{"topic": {"orders"}})
+			config := &gorest.SSEConfig{ReconnectDelay: time.Millisecond, MaxReconnects: 1}
+			for range gorest.NewClient().Subscribe(context.Background(), req, config) {
+			}
+			Expect(bodies).To(Equal([]string{"topic=orders", "topic=orders"}))
+		})
+
+		It("should stop with an HTTPError on a non-2xx response", func() {
+			server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+				w.WriteHeader(http.StatusUnauthorized)
+			}))
+			defer server.Close()
+
+			var errs []error
+			for _, err := range gorest.NewClient().Subscribe(context.Background(), gorest.NewRequest("GET", server.URL), nil) {
+				errs = append(errs, err)
+			}
+			Expect(errs).To(HaveLen(1))
+			Expect(gorest.IsUnauthorized(errs[0])).To(BeTrue())
+		})
+
+		It("should give up after the maximum number of reconnects", func() {
+			var connections int32
+			server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+				atomic.AddInt32(&connections, 1)
+				w.Header().Set("Content-Type", "text/event-stream")
+			}))
+			defer server.Close()
+
+			config := &gorest.SSEConfig{ReconnectDelay: time.Millisecond, MaxReconnects: 2}
+			var lastErr error
+			for _, err := range gorest.NewClient().Subscribe(context.Background(), gorest.NewRequest("GET", server.URL), config) {
+				lastErr = err
+			}
+			Expect(errors.Is(lastErr, io.EOF)).To(BeTrue())
+			Expect(atomic.LoadInt32(&connections)).To(Equal(int32(3)))
+		})
+
+		It("should stop on a line that exceeds the maximum size", func() {
+			var connections int32
+			server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+				atomic.AddInt32(&connections, 1)
+				w.Header().Set("Content-Type", "text/event-stream")
+				_, _ = fmt.Fprintf(w, "data: %s\n\n", strings.Repeat("x", 2<<20))
+			}))
+			defer server.Close()
+
+			config := &gorest.SSEConfig{ReconnectDelay: time.Millisecond}
+			var errs []error
+			for _, err := range gorest.NewClient().Subscribe(context.Background(), gorest.NewRequest("GET", server.URL), config) {
+				errs = append(errs, err)
+			}
+			Expect(errs).To(HaveLen(1))
+			Expect(errors.Is(errs[0], bufio.ErrTooLong)).To(BeTrue())
+			Expect(atomic.LoadInt32(&connections)).To(Equal(int32(1)))
+		})
+
+		It("should stop on a request that cannot be built", func() {
+			var connections int32
+			server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+				atomic.AddInt32(&connections, 1)
+			}))
+			defer server.Close()
+
+			req := gorest.NewRequest("GET", server.URL+"/streams/{id}/{topic}").WithPathParam("id", "1")
+			config := &gorest.SSEConfig{ReconnectDelay: time.Millisecond}
+			var errs []error
+			for _, err := range gorest.NewClient().Subscribe(context.Background(), req, config) {
+				errs = append(errs, err)
+				if len(errs) == 2 {
+					break
+				}
+			}
+			Expect(errs).To(HaveLen(1))
+			Expect(errs[0]).To(MatchError(ContainSubstring("missing path parameters: topic")))
+			Expect(atomic.LoadInt32(&connections)).To(BeZero())
+		})
+
+		It("should yield connection errors before reconnecting", func() {
+			server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {}))
+			server.Close()
+
+			config := &gorest.SSEConfig{ReconnectDelay: time.Millisecond}
+			var errs []error
+			for _, err := range gorest.NewClient().Subscribe(context.Background(), gorest.NewRequest("GET", server.URL), config) {
+				errs = append(errs, err)
+				if len(errs) == 2 {
+					break
+				}
+			}
+			Expect(errs).To(HaveLen(2))
+			Expect(errs[0]).To(HaveOccurred())
+			Expect(errs[1]).To(HaveOccurred())
+		})
+	})
+})
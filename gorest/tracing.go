@@ -0,0 +1,294 @@
+package gorest
+
+import (
+	"context"
+	"crypto/rand"
+	"encoding/hex"
+	"fmt"
+	"maps"
+	"net/http"
+	"net/url"
+	"strconv"
+	"strings"
+	"sync"
+	"time"
+)
+
+// SpanContext identifies a span within a trace, as propagated in the W3C traceparent and tracestate headers.
+type SpanContext struct {
+	TraceID [16]byte
+	SpanID  [8]byte
+	// Sampled reports whether the trace is recorded.
+	Sampled bool
+	// TraceState is the vendor-specific trace state, sent in the tracestate header if not empty.
+	TraceState string
+}
+
+// IsValid reports whether both the trace and span IDs are set.
+func (sc SpanContext) IsValid() bool {
+	return sc.TraceID != [16]byte{} && sc.SpanID != [8]byte{}
+}
+
+// TraceParent returns the value of the W3C traceparent header for sc.
+func (sc SpanContext) TraceParent() string {
+	flags := "00"
+	if sc.Sampled {
+		flags = "01"
+	}
+	return "00-" + hex.EncodeToString(sc.TraceID[:]) + "-" + hex.EncodeToString(sc.SpanID[:]) + "-" + flags
+}
+
+// SpanStatus is the status of a finished span.
+type SpanStatus int
+
+const (
+	// SpanStatusUnset is the default status.
+	SpanStatusUnset SpanStatus = iota
+	// SpanStatusOK marks a span as successful.
+	SpanStatusOK
+	// SpanStatusError marks a span as failed.
+	SpanStatusError
+)
+
+// Span is a single operation within a trace. It is implemented by adapters for tracing libraries.
+type Span interface {
+	// SpanContext returns the identifiers propagated to the server.
+	SpanContext() SpanContext
+	// SetAttribute sets an attribute, following the OpenTelemetry semantic conventions for HTTP clients.
+	SetAttribute(key string, value any)
+	// RecordError records an error that occurred during the span.
+	RecordError(err error)
+	// SetStatus sets the status of the span.
+	SetStatus(status SpanStatus, description string)
+	// End finishes the span.
+	End()
+}
+
+// Tracer starts spans. It keeps gorest independent of any tracing library: an adapter for, say,
+// OpenTelemetry only has to wrap its tracer. SpanRecorder is an in-memory implementation for tests.
+type Tracer interface {
+	// Start starts a client span named name as a child of the span in ctx, if any,
+	// and returns a context carrying the new span.
+	Start(ctx context.Context, name string) (context.Context, Span)
+}
+
+// baggageKey is the context key under which the baggage members of a context are stored.
+type baggageKey struct{}
+
+// ContextWithBaggage returns a context carrying the baggage member key=value in addition to those in ctx.
+// The TracingMiddleware sends the members in the W3C baggage header.
+func ContextWithBaggage(ctx context.Context, key, value string) context.Context {
+	baggage := BaggageFromContext(ctx)
+	baggage[key] = value
+	return context.WithValue(ctx, baggageKey{}, baggage)
+}
+
+// BaggageFromContext returns a copy of the baggage members in ctx.
+func BaggageFromContext(ctx context.Context) map[string]string {
+	baggage := make(map[string]string)
+	if parent, ok := ctx.Value(baggageKey{}).(map[string]string); ok {
+		for k, v := range parent {
+			baggage[k] = v
+		}
+	}
+	return baggage
+}
+
+// TracingMiddleware returns a middleware that wraps each request in a client span started with tracer,
+// records its method, URL, URL template, status and error, and injects the traceparent, tracestate
+// and baggage headers. Place it before RetryMiddleware in WithMiddlewares: each attempt then gets
+// a child span of its own, whose context is propagated to the server.
+// The span ends when the response headers are received.
+func TracingMiddleware(tracer Tracer) Middleware {
+	return func(next RoundTripFunc) RoundTripFunc {
+		return func(req *http.Request) (*http.Response, error) {
+			template := URLTemplate(req)
+			name := req.Method
+			if template != "" {
+				name += " " + template
+			}
+
+			ctx, span := tracer.Start(req.Context(), name)
+			defer span.End()
+			setSpanRequestAttributes(span, req, template)
+
+			ctx = withAttemptHook(ctx, func(attemptReq *http.Request, attempt int) (*http.Request, func(*http.Response, error)) {
+				attemptCtx, attemptSpan := tracer.Start(attemptReq.Context(), name)
+				setSpanRequestAttributes(attemptSpan, attemptReq, template)
+				if attempt > 1 {
+					attemptSpan.SetAttribute("http.request.resend_count", attempt-1)
+				}
+				attemptReq = attemptReq.WithContext(attemptCtx)
+				injectTraceHeaders(attemptCtx, attemptReq.Header, attemptSpan.SpanContext())
+				return attemptReq, func(resp *http.Response, err error) {
+					setSpanOutcome(attemptSpan, resp, err)
+					attemptSpan.End()
+				}
+			})
+			traced := req.Clone(ctx)
+			injectTraceHeaders(ctx, traced.Header, span.SpanContext())
+
+			resp, err := next(traced)
+			setSpanOutcome(span, resp, err)
+			return resp, err
+		}
+	}
+}
+
+func setSpanRequestAttributes(span Span, req *http.Request, template string) {
+	span.SetAttribute("http.request.method", req.Method)
+	span.SetAttribute("url.full", req.URL.Redacted())
+	span.SetAttribute("server.address", req.URL.Hostname())
+	if port, err := strconv.Atoi(req.URL.Port()); err == nil {
+		span.SetAttribute("server.port", port)
+	}
+	if template != "" {
+		span.SetAttribute("url.template", template)
+	}
+}
+
+// setSpanOutcome records the response status or error. 4xx and 5xx responses mark the span as failed.
+func setSpanOutcome(span Span, resp *http.Response, err error) {
+	if err != nil {
+		span.RecordError(err)
+		span.SetAttribute("error.type", fmt.Sprintf("%T", err))
+		span.SetStatus(SpanStatusError, err.Error())
+		return
+	}
+	span.SetAttribute("http.response.status_code", resp.StatusCode)
+	if resp.StatusCode >= 400 {
+		span.SetAttribute("error.type", strconv.Itoa(resp.StatusCode))
+		span.SetStatus(SpanStatusError, "")
+	}
+}
+
+// injectTraceHeaders sets the W3C trace context and baggage headers.
+func injectTraceHeaders(ctx context.Context, header http.Header, sc SpanContext) {
+	if sc.IsValid() {
+		header.Set("Traceparent", sc.TraceParent())
+		if sc.TraceState != "" {
+			header.Set("Tracestate", sc.TraceState)
+		} else {
+			header.Del("Tracestate")
+		}
+	}
+	baggage := BaggageFromContext(ctx)
+	if len(baggage) == 0 {
+		return
+	}
+	members := make([]string, 0, len(baggage))
+	for _, key := range sortedKeys(baggage) {
+		members = append(members, key+"="+url.PathEscape(baggage[key]))
+	}
+	header.Set("Baggage", strings.Join(members, ","))
+}
+
+// RecordedSpan is a span finished by a SpanRecorder.
+type RecordedSpan struct {
+	Name        string
+	SpanContext SpanContext
+	// Parent is the context of the parent span, or the zero value for root spans.
+	Parent            SpanContext
+	Attributes        map[string]any
+	Errors            []error
+	Status            SpanStatus
+	StatusDescription string
+	StartTime         time.Time
+	EndTime           time.Time
+}
+
+// SpanRecorder is a Tracer that keeps finished spans in memory, for use in tests.
+type SpanRecorder struct {
+	mu    sync.Mutex
+	spans []RecordedSpan
+}
+
+// NewSpanRecorder returns an empty SpanRecorder.
+func NewSpanRecorder() *SpanRecorder {
+	return &SpanRecorder{}
+}
+
+// recordedSpanKey is the context key under which a SpanRecorder stores the current span.
+type recordedSpanKey struct{}
+
+// Start implements Tracer. Spans started from a context without a recorded span begin a new sampled trace.
+func (r *SpanRecorder) Start(ctx context.Context, name string) (context.Context, Span) {
+	span := &recorderSpan{recorder: r, data: RecordedSpan{
+		Name:       name,
+		Attributes: make(map[string]any),
+		StartTime:  time.Now(),
+	}}
+	if parent, ok := ctx.Value(recordedSpanKey{}).(*recorderSpan); ok {
+		span.data.Parent = parent.SpanContext()
+		span.data.SpanContext = span.data.Parent
+	} else {
+		_, _ = rand.Read(span.data.SpanContext.TraceID[:])
+		span.data.SpanContext.Sampled = true
+	}
+	_, _ = rand.Read(span.data.SpanContext.SpanID[:])
+	return context.WithValue(ctx, recordedSpanKey{}, span), span
+}
+
+// Spans returns the finished spans in the order they ended.
+func (r *SpanRecorder) Spans() []RecordedSpan {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+	return append([]RecordedSpan(nil), r.spans...)
+}
+
+// Reset discards the finished spans.
+func (r *SpanRecorder) Reset() {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+	r.spans = nil
+}
+
+// recorderSpan is a Span started by a SpanRecorder.
+type recorderSpan struct {
+	recorder *SpanRecorder
+	mu       sync.Mutex
+	data     RecordedSpan
+	ended    bool
+}
+
+func (s *recorderSpan) SpanContext() SpanContext {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	return s.data.SpanContext
+}
+
+func (s *recorderSpan) SetAttribute(key string, value any) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	s.data.Attributes[key] = value
+}
+
+func (s *recorderSpan) RecordError(err error) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	s.data.Errors = append(s.data.Errors, err)
+}
+
+func (s *recorderSpan) SetStatus(status SpanStatus, description string) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	s.data.Status = status
+	s.data.StatusDescription = description
+}
+
+func (s *recorderSpan) End() {
+	s.mu.Lock()
+	if s.ended {
+		s.mu.Unlock()
+		return
+	}
+	s.ended = true
+	s.data.EndTime = time.Now()
+	data := s.data
+	data.Attributes = maps.Clone(s.data.Attributes)
+	s.mu.Unlock()
+
+	s.recorder.mu.Lock()
+	defer s.recorder.mu.Unlock()
+	s.recorder.spans = append(s.recorder.spans, data)
+}
@@ -0,0 +1,174 @@
+package gorest_test
+
+import (
+	"context"
+	"errors"
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"sync"
+	"sync/atomic"
+
+	. "github.com/onsi/ginkgo/v2"
+	. "github.com/onsi/gomega"
+
+	"gorest/gorest"
+)
+
+var _ = Describe("TracingMiddleware", func() {
+	var (
+		recorder *gorest.SpanRecorder
+		mu       sync.Mutex
+		headers  []http.Header
+		statuses []int
+		calls    int32
+		server   *httptest.Server
+	)
+
+	BeforeEach(func() {
+		recorder = gorest.NewSpanRecorder()
+		headers = nil
+		calls = 0
+		statuses = []int{200}
+		server = httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+			mu.Lock()
+			defer mu.Unlock()
+			headers = append(headers, r.Header.Clone())
+			n := int(atomic.AddInt32(&calls, 1))
+			w.WriteHeader(statuses[min(n, len(statuses))-1])
+		}))
+	})
+
+	AfterEach(func() {
+		server.Close()
+	})
+
+	It("should record a client span and propagate its context", func() {
+		client := gorest.NewClient(gorest.WithBaseURL(server.URL), gorest.WithMiddlewares(gorest.TracingMiddleware(recorder)))
+		ctx := gorest.ContextWithBaggage(context.Background(), "tenant", "acme corp")
+		ctx = gorest.ContextWithBaggage(ctx, "region", "eu")
+		_, err := client.Do(ctx, gorest.NewRequest("GET", "/users/{id}").WithPathParam("id", "42"))
+		Expect(err).NotTo(HaveOccurred())
+
+		spans := recorder.Spans()
+		Expect(spans).To(HaveLen(1))
+		span := spans[0]
+		Expect(span.Name).To(Equal("GET /users/{id}"))
+		Expect(span.Parent.IsValid()).To(BeFalse())
+		Expect(span.Attributes).To(HaveKeyWithValue("http.request.method", "GET"))
+		Expect(span.Attributes).To(HaveKeyWithValue("url.full", server.URL+"/users/42"))
+		Expect(span.Attributes).To(HaveKeyWithValue("url.template", "/users/{id}"))
+		Expect(span.Attributes).To(HaveKeyWithValue("server.address", "127.0.0.1"))
+		Expect(span.Attributes).To(HaveKeyWithValue("http.response.status_code", 200))
+		Expect(span.Status).To(Equal(gorest.SpanStatusUnset))
+
+		Expect(headers[0].Get("Traceparent")).To(Equal(span.SpanContext.TraceParent()))
+		Expect(headers[0].Get("Traceparent")).To(MatchRegexp(`^00-[0-9a-f]{32}-[0-9a-f]{16}-01$`))
+		Expect(headers[0].Get("Baggage")).To(Equal("region=eu,tenant=acme%20corp"))
+	})
+
+	It("should create a child span per retry attempt", func() {
+		statuses = []int{503, 503, 200}
+		client := gorest.NewClient(gorest.WithMiddlewares(
+			gorest.TracingMiddleware(recorder),
+			gorest.RetryMiddlewareWithConfig(&gorest.RetryConfig{Attempts: 3, Backoff: gorest.ConstantBackoff(0)}),
+		))
+		_, err := client.Do(context.Background(), gorest.NewRequest("GET", server.URL+"/items"))
+		Expect(err).NotTo(HaveOccurred())
+
+		spans := recorder.Spans()
+		Expect(spans).To(HaveLen(4))
+		parent := spans[3]
+		Expect(parent.Name).To(Equal("GET"))
+		Expect(parent.Attributes).To(HaveKeyWithValue("http.response.status_code", 200))
+		for i, attempt := range spans[:3] {
+			Expect(attempt.Parent).To(Equal(parent.SpanContext))
+			Expect(attempt.SpanContext.TraceID).To(Equal(parent.SpanContext.TraceID))
+			Expect(headers[i].Get("Traceparent")).To(Equal(attempt.SpanContext.TraceParent()))
+		}
+		Expect(spans[0].Attributes).NotTo(HaveKey("http.request.resend_count"))
+		Expect(spans[0].Status).To(Equal(gorest.SpanStatusError))
+		Expect(spans[0].Attributes).To(HaveKeyWithValue("error.type", "503"))
+		Expect(spans[2].Attributes).To(HaveKeyWithValue("http.request.resend_count", 2))
+		Expect(spans[2].Status).To(Equal(gorest.SpanStatusUnset))
+	})
+
+	It("should record errors", func() {
+		failing := func(next gorest.RoundTripFunc) gorest.RoundTripFunc {
+			return func(req *http.Request) (*http.Response, error) {
+				return nil, errors.New("connection refused")
+			}
+		}
+		client := gorest.NewClient(gorest.WithMiddlewares(gorest.TracingMiddleware(recorder), failing))
+		_, err := client.Do(context.Background(), gorest.NewRequest("GET", server.URL))
+		Expect(err).To(HaveOccurred())
+
+		span := recorder.Spans()[0]
+		Expect(span.Status).To(Equal(gorest.SpanStatusError))
+		Expect(span.Errors).To(HaveLen(1))
+		Expect(span.StatusDescription).To(Equal("connection refused"))
+		Expect(span.Attributes).To(HaveKeyWithValue("error.type", "*errors.errorString"))
+	})
+
+	It("should not share the route and attempts with requests sent while handling the request", func() {
+		inner := gorest.NewClient(gorest.WithMiddlewares(
+			gorest.TracingMiddleware(recorder),
+			gorest.RetryMiddlewareWithConfig(&gorest.RetryConfig{Attempts: 2, Backoff: gorest.ConstantBackoff(0)}),
+		))
+		nested := func(next gorest.RoundTripFunc) gorest.RoundTripFunc {
+			return func(req *http.Request) (*http.Response, error) {
+				if _, err := inner.Do(req.Context(), gorest.NewRequest("GET", server.URL+"/token")); err != nil {
+					return nil, err
+				}
+				return next(req)
+			}
+		}
+		client := gorest.NewClient(gorest.WithBaseURL(server.URL), gorest.WithMiddlewares(
+			gorest.TracingMiddleware(recorder),
+			gorest.RetryMiddlewareWithConfig(&gorest.RetryConfig{Attempts: 2, Backoff: gorest.ConstantBackoff(0)}),
+			nested,
+		))
+		_, err := client.Do(context.Background(), gorest.NewRequest("GET", "/users/{id}").WithPathParam("id", "42"))
+		Expect(err).NotTo(HaveOccurred())
+
+		var names []string
+		for _, span := range recorder.Spans() {
+			names = append(names, span.Name)
+		}
+		Expect(names).To(Equal([]string{"GET", "GET", "GET /users/{id}", "GET /users/{id}"}))
+	})
+
+	It("should continue the trace of the caller and send its trace state", func() {
+		ctx, parent := recorder.Start(context.Background(), "handler")
+		client := gorest.NewClient(gorest.WithMiddlewares(gorest.TracingMiddleware(stateTracer{recorder})))
+		_, err := client.Do(ctx, gorest.NewRequest("GET", server.URL))
+		Expect(err).NotTo(HaveOccurred())
+		parent.End()
+
+		spans := recorder.Spans()
+		Expect(spans).To(HaveLen(2))
+		Expect(spans[0].Parent).To(Equal(spans[1].SpanContext))
+		Expect(headers[0].Get("Tracestate")).To(Equal("vendor=value"))
+		Expect(strings.Split(headers[0].Get("Traceparent"), "-")[1]).To(Equal(strings.Split(spans[1].SpanContext.TraceParent(), "-")[1]))
+	})
+})
+
+// stateTracer adds a trace state to the spans of a SpanRecorder.
+type stateTracer struct {
+	*gorest.SpanRecorder
+}
+
+func (t stateTracer) Start(ctx context.Context, name string) (context.Context, gorest.Span) {
+	ctx, span := t.SpanRecorder.Start(ctx, name)
+	return ctx, stateSpan{span}
+}
+
+type stateSpan struct {
+	gorest.Span
+}
+
+func (s stateSpan) SpanContext() gorest.SpanContext {
+	sc := s.Span.SpanContext()
+	sc.TraceState = "vendor=value"
+	return sc
+}
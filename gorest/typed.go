@@ -0,0 +1,81 @@
+package gorest
+
+import (
+	"context"
+	"encoding/json"
+	"errors"
+	"io"
+	"net/http"
+)
+
+// APIError is returned by DoJSONWithError when a non-2xx response body could be decoded into E.
+// It wraps the underlying *HTTPError, so errors.As and helpers such as IsNotFound keep working.
+type APIError[E any] struct {
+	*HTTPError
+	// Value holds the decoded error body.
+	Value E
+}
+
+// Unwrap returns the underlying *HTTPError.
+func (e *APIError[E]) Unwrap() error {
+	return e.HTTPError
+}
+
+// DoJSON sends req using c and decodes a 2xx JSON response body into a value of type T.
+// Non-2xx responses are returned as *HTTPError. An empty response body yields the zero value of T.
+// The returned Response carries the status and headers; its body has already been consumed.
+func DoJSON[T any](ctx context.Context, c *Client, req *Request) (T, *Response, error) {
+	var zero T
+	resp, err := c.Do(ctx, req)
+	if err != nil {
+		return zero, nil, err
+	}
+	if resp.StatusCode < 200 || resp.StatusCode >= 300 {
+		return zero, resp, newHTTPError(resp.Request, resp.Response)
+	}
+	var v T
+	if err := resp.JSON(&v); err != nil && !errors.Is(err, io.EOF) {
+		return zero, resp, err
+	}
+	return v, resp, nil
+}
+
+// DoJSONWithError is like DoJSON, but additionally decodes non-2xx JSON response bodies into a value of type E.
+// On success of that decoding the returned error is an *APIError[E]; otherwise it is the plain *HTTPError.
+// Error bodies are subject to the same size limit as HTTPError.Body.
+func DoJSONWithError[T, E any](ctx context.Context, c *Client, req *Request) (T, *Response, error) {
+	v, resp, err := DoJSON[T](ctx, c, req)
+	var httpErr *HTTPError
+	if err == nil || !errors.As(err, &httpErr) || len(httpErr.Body) == 0 {
+		return v, resp, err
+	}
+	var errBody E
+	if json.Unmarshal(httpErr.Body, &errBody) != nil {
+		return v, resp, err
+	}
+	return v, resp, &APIError[E]{HTTPError: httpErr, Value: errBody}
+}
+
+// GetJSON is a convenience function for sending GET requests and decoding the JSON response into T.
+func GetJSON[T any](ctx context.Context, c *Client, url string, headers map[string]string) (T, *Response, error) {
+	req := NewRequest(http.MethodGet, url).WithHeaders(headers)
+	return DoJSON[T](ctx, c, req)
+}
+
+// PostJSON is a convenience function for sending a JSON-encoded Req body with POST and decoding the JSON response into Resp.
+func PostJSON[Req, Resp any](ctx context.Context, c *Client, url string, body Req, headers map[string]string) (Resp, *Response, error) {
+	req := NewRequest(http.MethodPost, url).WithJSONBody(body).WithHeaders(headers)
+	return DoJSON[Resp](ctx, c, req)
+}
+
+// PutJSON is a convenience function for sending a JSON-encoded Req body with PUT and decoding the JSON response into Resp.
+func PutJSON[Req, Resp any](ctx context.Context, c *Client, url string, body Req, headers map[string]string) (Resp, *Response, error) {
+	req := NewRequest(http.MethodPut, url).WithJSONBody(body).WithHeaders(headers)
+	return DoJSON[Resp](ctx, c, req)
+}
+
+// PatchJSON is a convenience function for sending a JSON-encoded Req body with PATCH and decoding the JSON response into Resp.
+func PatchJSON[Req, Resp any](ctx context.Context, c *Client, url string, body Req, headers map[string]string) (Resp, *Response, error) {
+	req := NewRequest(http.MethodPatch, url).WithJSONBody(body).WithHeaders(headers)
+	return DoJSON[Resp](ctx, c, req)
+}
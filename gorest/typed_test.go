@@ -0,0 +1,104 @@
+package gorest_test
+
+import (
+	"context"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"net/http"
+	"net/http/httptest"
+
+	. "github.com/onsi/ginkgo/v2"
+	. "github.com/onsi/gomega"
+
+	"gorest/gorest"
+)
+
+type user struct {
+	ID   int    `json:"id"`
+	Name string `json:"name"`
+}
+
+type apiProblem struct {
+	Code    string `json:"code"`
+	Message string `json:"message"`
+}
+
+var _ = Describe("Typed JSON helpers", func() {
+	var testServer *httptest.Server
+
+	BeforeEach(func() {
+		testServer = httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+			switch r.URL.Path {
+			case "/user":
+				w.Header().Set("Content-Type", "application/json")
+				_, _ = fmt.Fprint(w, `{"id": 7, "name": "ada"}`)
+			case "/echo":
+				var u user
+				_ = json.NewDecoder(r.Body).Decode(&u)
+				u.ID = 42
+				w.Header().Set("Content-Type", "application/json")
+				w.WriteHeader(http.StatusCreated)
+				_ = json.NewEncoder(w).Encode(u)
+			case "/empty":
+				w.WriteHeader(http.StatusNoContent)
+			case "/problem":
+				w.Header().Set("Content-Type", "application/json")
+				w.WriteHeader(http.StatusUnprocessableEntity)
+				_, _ = fmt.Fprint(w, `{"code": "invalid", "message": "name is required"}`)
+			default:
+				w.WriteHeader(http.StatusNotFound)
+				_, _ = fmt.Fprint(w, "not json")
+			}
+		}))
+	})
+
+	AfterEach(func() {
+		testServer.Close()
+	})
+
+	It("should decode a JSON response into the requested type with GetJSON", func() {
+		u, resp, err := gorest.GetJSON[user](context.Background(), gorest.NewClient(), testServer.URL+"/user", nil)
+		Expect(err).NotTo(HaveOccurred())
+		Expect(resp.StatusCode).To(Equal(http.StatusOK))
+		Expect(u).To(Equal(user{ID: 7, Name: "ada"}))
+	})
+
+	It("should encode the request and decode the response with PostJSON", func() {
+		u, resp, err := gorest.PostJSON[user, user](context.Background(), gorest.NewClient(), testServer.URL+"/echo", user{Name: "grace"}, nil)
+		Expect(err).NotTo(HaveOccurred())
+		Expect(resp.StatusCode).To(Equal(http.StatusCreated))
+		Expect(u).To(Equal(user{ID: 42, Name: "grace"}))
+	})
+
+	It("should return the zero value for empty bodies", func() {
+		u, resp, err := gorest.DoJSON[*user](context.Background(), gorest.NewClient(), gorest.NewRequest("DELETE", testServer.URL+"/empty"))
+		Expect(err).NotTo(HaveOccurred())
+		Expect(resp.StatusCode).To(Equal(http.StatusNoContent))
+		Expect(u).To(BeNil())
+	})
+
+	It("should return an HTTPError for non-2xx responses", func() {
+		_, resp, err := gorest.GetJSON[user](context.Background(), gorest.NewClient(), testServer.URL+"/missing", nil)
+		Expect(resp.StatusCode).To(Equal(http.StatusNotFound))
+		Expect(gorest.IsNotFound(err)).To(BeTrue())
+	})
+
+	It("should decode error bodies with DoJSONWithError", func() {
+		req := gorest.NewRequest("POST", testServer.URL+"/problem").WithJSONBody(user{})
+		_, _, err := gorest.DoJSONWithError[user, apiProblem](context.Background(), gorest.NewClient(gorest.WithStatusErrors(true)), req)
+		var apiErr *gorest.APIError[apiProblem]
+		Expect(errors.As(err, &apiErr)).To(BeTrue())
+		Expect(apiErr.Value).To(Equal(apiProblem{Code: "invalid", Message: "name is required"}))
+		Expect(gorest.IsStatus(err, http.StatusUnprocessableEntity)).To(BeTrue())
+	})
+
+	It("should fall back to the plain HTTPError when the error body cannot be decoded", func() {
+		_, _, err := gorest.DoJSONWithError[user, apiProblem](context.Background(), gorest.NewClient(), gorest.NewRequest("GET", testServer.URL+"/missing"))
+		var apiErr *gorest.APIError[apiProblem]
+		Expect(errors.As(err, &apiErr)).To(BeFalse())
+		var httpErr *gorest.HTTPError
+		Expect(errors.As(err, &httpErr)).To(BeTrue())
+		Expect(string(httpErr.Body)).To(Equal("not json"))
+	})
+})